@@ -60,6 +60,12 @@ window.addEventListener('load', function() {
             handleNotifyEndgameScoring(msg.Data)
         } else if (msg.SType == stypeNotifyComplete) {
             handleNotifyComplete(msg.Data)
+        } else if (msg.SType == stypeNotifyAbandoned) {
+            handleNotifyAbandoned(msg.Data)
+        } else if (msg.SType == stypeNotifyRematch) {
+            handleNotifyRematch(msg.Data)
+        } else if (msg.SType == stypeNotifyBotThoughts) {
+            handleNotifyBotThoughts(msg.Data)
         } else {
             printMsg('unhandled stype: '+msg.SType+' data: '+msg.Data)
         }
@@ -397,6 +403,23 @@ function handleNotifyComplete(d) {
     status = gameStatusComplete
 }
 
+function handleNotifyAbandoned(d) {
+    status = gameStatusAbandoned
+}
+
+function handleNotifyRematch(d) {
+    window.location.href = 'https://'+location.hostname+'/g/'+d.Id
+}
+
+// Only sent after sendRequestBotThoughts(true); for now they go to the console.
+function handleNotifyBotThoughts(d) {
+    printMsg('P'+d.Player+' ('+d.Identity.Name+'): '+d.Thoughts.Summary)
+    var plans = d.Thoughts.Plans || []
+    plans.forEach(function (p) {
+        printMsg('    '+p.Goal+' route '+p.RouteId+': '+p.Fitness+' '+p.Calculation)
+    })
+}
+
 // This has to look inside groups
 function pieceAtLocation(l) {
     var ls = lToS(l)
@@ -1112,6 +1135,14 @@ function sendEndbump() {
     ws.send(msg);
 }
 
+function sendRequestBotThoughts(enabled) {
+    if (!ws) {
+        return
+    }
+    var msg = '{"CType":'+ctypeRequestBotThoughts+',"Data":{"Enabled":'+enabled+'}}';
+    ws.send(msg);
+}
+
 function stopwatch() {
     Array.from(getEls('stopwatch-active')).forEach(function (el) {
         var ms = el.innerHTML.split(':')
@@ -22,6 +22,18 @@ const stypeNotifyEndBump = 19;
 const stypeNotifyScoringBegin = 20;
 const stypeNotifyEndgameScoring = 21;
 const stypeNotifyComplete = 22;
+const stypeNotifyRematchRequest = 23;
+const stypeNotifyRematch = 24;
+const stypeNotifyResign = 25;
+const stypeNotifyAbandonVote = 26;
+const stypeNotifyAbandoned = 27;
+const stypeNotifyGameOptions = 28;
+const stypeNotifyColor = 29;
+const stypeNotifyLockSeat = 30;
+const stypeNotifyReadyCheck = 31;
+const stypeNotifyReady = 32;
+const stypeNotifyMatchFound = 33;
+const stypeNotifyBotThoughts = 34;
 
 const ctypeRequestSignup = 1
 const ctypeRequestSignin = 2
@@ -34,24 +46,41 @@ const ctypeStartGame = 8;
 const ctypeDoSubaction = 9;
 const ctypeEndTurn = 10;
 const ctypeEndBump = 11;
+const ctypeRequestRematch = 12;
+const ctypeAnswerRematch = 13;
+const ctypeResign = 14;
+const ctypeAbandonVote = 15;
+const ctypeUpdateGameOptions = 16;
+const ctypeRequestColor = 17;
+const ctypeKickSeat = 18;
+const ctypeLockSeat = 19;
+const ctypeReady = 20;
+const ctypeEnqueueMatch = 21;
+const ctypeDequeueMatch = 22;
+const ctypeBotThoughts = 23;
+const ctypeRequestBotThoughts = 24;
+const ctypeRequestFullGame = 25;
 
 const identityTypeNone = 0;
 const identityTypeConnection = 1;
 const identityTypeGuest = 2;
 const identityTypeBot = 3;
+const identityTypeExternalBot = 4;
 
 const gameStatusCreating = 1;
 const gameStatusRunning = 2;
 const gameStatusAbandoned = 3;
 const gameStatusScoring = 4;
 const gameStatusComplete = 5;
+const gameStatusReadyCheck = 6;
 
 var gameStatusNames = {
     1: 'Creating',
     2: 'Running',
     3: 'Abandoned',
     4: 'Scoring',
-    5: 'Complete'
+    5: 'Complete',
+    6: 'ReadyCheck'
 }
 
 const turnStateTypeNone = 0;
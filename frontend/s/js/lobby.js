@@ -32,6 +32,8 @@ window.addEventListener('load', function() {
             refreshLobby(msg.Data)
         } else if (msg.SType == stypeNotifyCreateGame) {
             window.location.href = 'https://'+location.hostname+'/g/'+msg.Data.Id
+        } else if (msg.SType == stypeNotifyMatchFound) {
+            window.location.href = 'https://'+location.hostname+'/g/'+msg.Data.Id
         }
     };
     ws.onerror = function(evt) {
@@ -1,7 +1,6 @@
 package bot
 
 import (
-    "encoding/json"
     "fmt"
     "time"
     "local/hansa/log"
@@ -14,6 +13,22 @@ type Bot struct {
     brain Brain
     inMsg chan message.Server
     outMsg chan message.Client
+
+    // How long we wait before each response, so people can follow along (see
+    // pacing.go).  watched is set by the game's goroutine, so it's atomic.
+    pacing simple.BotPacing
+    watched int32
+    animating time.Time
+
+    // Whether the last thing we sent was an EndTurn or EndBump and the game
+    // hasn't moved on since, so a client error means it wouldn't take it.
+    ended bool
+
+    // The WeightFile (Name@Version) the brain plays, or "" if it plays none.
+    weights string
+
+    // If set, a panic is handed here instead of stopping the server.
+    recovered func(i simple.Identity, r interface{})
 }
 
 func (b *Bot) Run() {
@@ -21,19 +36,14 @@ func (b *Bot) Run() {
     for msg := range b.inMsg {
         b.dispatch(msg)
     }
+    if e, ok := b.brain.(*EngineBrain); ok {
+        e.stop()
+    }
 }
 
 func (b *Bot) Send(msg message.Server) {
-    // deepcopy
-    bytes, err := json.Marshal(msg)
-    if err != nil {
-        panic(fmt.Sprintf("Bot: Error marshalling, giving up: '%s' message.Server: %v", err, msg))
-    }
-    msg, err = message.UnmarshalServer(bytes)
-    if err != nil {
-        panic(fmt.Sprintf("Bot: Error unmarshalling, giving up: '%s' message.Server: %v", err, msg))
-    }
-    b.inMsg <- msg
+    // The game keeps using what it sent us.
+    b.inMsg <- msg.Clone()
 }
 
 func (b *Bot) Read() chan message.Client {
@@ -44,36 +54,100 @@ func (b *Bot) Identity() simple.Identity {
     return b.identity
 }
 
+// So each game can record what its bots played (see WeightFile).
+func (b *Bot) Weights() string {
+    return b.weights
+}
+
 func (b *Bot) Done() {
     close(b.inMsg)
 }
 
 func (b *Bot) dispatch(m message.Server) {
+    start := time.Now()
+    b.observe(m)
+    if r, ok := b.brain.(resyncer); ok && r.resyncing() && m.SType != message.NotifyFullGame {
+        b.log(fmt.Sprintf("Resyncing, dropping SType message.%s", m.SType))
+        return
+    }
+
     var responses []message.Client
+    switch m.SType {
+        case message.NotifyNextTurn, message.NotifyEndBump, message.NotifyFullGame:
+            b.ended = false
+    }
     switch t := m.SType; t {
         case message.NotifyStartGame:
             b.brain.handleStartGame(m.Data.(message.NotifyStartGameData))
+        case message.NotifyFullGame:
+            responses = b.brain.handleFullGame(m.Data.(message.NotifyFullGameData))
         case message.NotifySubaction:
             responses = b.brain.handleNotifySubaction(m.Data.(message.NotifySubactionData))
         case message.NotifyNextTurn:
             responses = b.brain.handleNotifyNextTurn(m.Data.(message.NotifyNextTurnData))
         case message.NotifySubactionError:
-            b.brain.handleNotifySubactionError(m.Data.(message.NotifySubactionErrorData))
+            responses = b.brain.handleNotifySubactionError(m.Data.(message.NotifySubactionErrorData))
         case message.NotifyEndBump:
             responses = b.brain.handleNotifyEndBump(m.Data.(message.NotifyEndBumpData))
+        case message.NotifyNotification:
+            responses = b.handleNotification(m.Data.(message.NotifyNotificationData))
         default:
             b.log(fmt.Sprintf("Ignoring SType message.%s", t))
     }
+
+    // Thoughts go out ahead of what they explain, without the pacing delay.
+    if t, ok := b.brain.(thinker); ok {
+        for _, d := range t.takeThoughts() {
+            b.outMsg <- message.Client{
+                CType: message.BotThoughts,
+                Data: d,
+            }
+        }
+    }
     if responses != nil {
-        for _, r := range responses {
-            time.Sleep(200 * time.Millisecond)
+        considered := 0
+        if d, ok := b.brain.(deliberator); ok {
+            considered = d.takeConsidered()
+        }
+        for i, r := range responses {
+            time.Sleep(b.delay(i, considered, start))
             b.outMsg <- r
         }
+        if len(responses) > 0 {
+            last := responses[len(responses)-1].CType
+            b.ended = last == message.EndTurn || last == message.EndBump
+        }
+    }
+}
+
+// Otherwise the game would wait on us forever; brains that can't resync have
+// nothing better to do than ignore it.
+func (b *Bot) handleNotification(d message.NotifyNotificationData) []message.Client {
+    r, ok := b.brain.(resyncer)
+    if !ok || !b.ended || d.Type != message.NotificationError {
+        b.log(fmt.Sprintf("Ignoring notification %s: %s", d.Header, d.Content))
+        return nil
     }
+    b.ended = false
+    b.log(fmt.Sprintf("The game rejected how we ended: %s: %s, resyncing", d.Header, d.Content))
+    return r.rejected()
+}
+
+// Brains that can explain their decisions (see message.BotThoughtsData).
+type thinker interface {
+    takeThoughts() []message.BotThoughtsData
 }
 
 func (b *Bot) panicking() {
     if r := recover(); r != nil {
+        if b.recovered != nil {
+            b.recovered(b.identity, r)
+
+            // Until Done, so the game never blocks sending to us.
+            for range b.inMsg {
+            }
+            return
+        }
         s := fmt.Sprintf("bot panic (%v)", b)
         log.Stop(s, r)
         panic(r)
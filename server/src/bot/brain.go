@@ -6,11 +6,22 @@ import (
 
 type Brain interface {
     handleStartGame(d message.NotifyStartGameData)
+    handleFullGame(d message.NotifyFullGameData) []message.Client
     handleNotifySubaction(d message.NotifySubactionData) []message.Client
     handleNotifyNextTurn(d message.NotifyNextTurnData) []message.Client
-    handleNotifySubactionError(d message.NotifySubactionErrorData)
+    handleNotifySubactionError(d message.NotifySubactionErrorData) []message.Client
     handleNotifyEndBump(d message.NotifyEndBumpData) []message.Client
     // handleUndo
     // handleEndTurn
 }
 
+
+// Never answers (see NewSilentManager).
+type silentBrain struct {}
+
+func (b silentBrain) handleStartGame(d message.NotifyStartGameData) {}
+func (b silentBrain) handleFullGame(d message.NotifyFullGameData) []message.Client { return nil }
+func (b silentBrain) handleNotifySubaction(d message.NotifySubactionData) []message.Client { return nil }
+func (b silentBrain) handleNotifyNextTurn(d message.NotifyNextTurnData) []message.Client { return nil }
+func (b silentBrain) handleNotifySubactionError(d message.NotifySubactionErrorData) []message.Client { return nil }
+func (b silentBrain) handleNotifyEndBump(d message.NotifyEndBumpData) []message.Client { return nil }
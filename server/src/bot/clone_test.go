@@ -0,0 +1,145 @@
+package bot
+
+import (
+    "encoding/json"
+    "reflect"
+    "testing"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// A 5 player table partway through a game: pieces on some routes, offices,
+// virtual offices, Coellen and a few upgrades.
+func cloneTestTable() simple.Table {
+    t := simple.Table{
+        Board: simple.NewBase45Board(),
+        PlayerBoards: simple.NewBasePlayerBoards(),
+        Scores: []int{3, 0, 7, 2, 5},
+        Tokens: simple.NewBaseStartTokens(),
+    }
+    for i, _ := range t.Board.Routes {
+        pb := t.PlayerBoards[i % len(t.PlayerBoards)]
+        if i % 3 == 0 {
+            t.Board.Routes[i].Spots[0] = simple.Piece{PlayerColor: pb.Color, Shape: simple.CubeShape}
+        }
+    }
+    for i, _ := range t.Board.Cities {
+        c := &t.Board.Cities[i]
+        pb := t.PlayerBoards[i % len(t.PlayerBoards)]
+        if i % 2 == 0 && len(c.Offices) > 0 {
+            c.Offices[0].Piece = simple.Piece{PlayerColor: pb.Color, Shape: c.Offices[0].Shape}
+        }
+        if i % 7 == 0 {
+            c.VirtualOffices = append(c.VirtualOffices, simple.Piece{PlayerColor: pb.Color, Shape: simple.CubeShape})
+        }
+        if len(c.Coellen.Spots) > 0 {
+            c.Coellen.Spots[0].Piece = simple.Piece{PlayerColor: pb.Color, Shape: simple.DiscShape}
+        }
+    }
+    t.PlayerBoards[0].Books[0] = simple.Piece{}
+    t.PlayerBoards[2].Actions[0] = simple.Piece{}
+    return t
+}
+
+func cloneTestMessages() []message.Server {
+    t := cloneTestTable()
+    return []message.Server{
+        message.Server{SType: message.NotifyStartGame, Data: message.NotifyStartGameData{
+            Table: t,
+            Order: []simple.Identity{simple.NewBotIdentity("B1", "Derek (Bot)")},
+        }},
+        message.Server{SType: message.NotifyFullGame, Data: message.NotifyFullGameData{
+            Table: t,
+            Scores: []int{1, 2, 3, 4, 5},
+            FinalScores: []map[simple.ScoreType]int{map[simple.ScoreType]int{simple.ScoreType(1): 4}},
+            Elapsed: []int64{1, 2, 3, 4, 5},
+        }},
+        message.Server{SType: message.NotifySubaction, Data: message.NotifySubactionData{
+            Subaction: simple.Subaction{Piece: simple.Piece{PlayerColor: simple.RedPlayerColor, Shape: simple.CubeShape}},
+            Scores: []int{0, 1, 0, 0, 0},
+        }},
+        message.Server{SType: message.NotifyNextTurn, Data: message.NotifyNextTurnData{Elapsed: []int64{9, 8}}},
+        message.Server{SType: message.NotifyBotThoughts, Data: message.NotifyBotThoughtsData{
+            Thoughts: message.BotThoughtsData{Summary: "x", Plans: []message.PlanThought{message.PlanThought{Goal: "Full Points"}}},
+        }},
+        message.Server{SType: message.NotifyLockSeat, Data: message.NotifyLockSeatData{Index: 2, Lock: true}},
+    }
+}
+
+// What Bot.Send used to do.
+func jsonClone(tb testing.TB, m message.Server) message.Server {
+    bytes, err := json.Marshal(m)
+    if err != nil {
+        tb.Fatal(err)
+    }
+    r, err := message.UnmarshalServer(bytes)
+    if err != nil {
+        tb.Fatal(err)
+    }
+    return r
+}
+
+func TestCloneMatchesJson(t *testing.T) {
+    for _, m := range cloneTestMessages() {
+        c := m.Clone()
+        if !reflect.DeepEqual(c.Data, jsonClone(t, m).Data) {
+            t.Errorf("%s: Clone differs from the json round trip", m.SType)
+        }
+        if !reflect.DeepEqual(c, m) {
+            t.Errorf("%s: Clone differs from the original", m.SType)
+        }
+    }
+}
+
+func TestCloneSharesNothing(t *testing.T) {
+    orig := cloneTestTable()
+    want := orig.Json()
+    c := orig.Clone()
+    c.Board.Routes[0].Spots[0] = simple.Piece{}
+    c.Board.Routes[1].Bumped = append(c.Board.Routes[1].Bumped, simple.Piece{PlayerColor: simple.RedPlayerColor})
+    c.Board.Cities[0].Offices[0].Piece = simple.Piece{}
+    c.Board.Cities[0].VirtualOffices[0] = simple.Piece{}
+    c.PlayerBoards[1].Supply[0] = simple.Piece{}
+    c.PlayerBoards[1].UsedTokens = append(c.PlayerBoards[1].UsedTokens, simple.LevelupToken)
+    c.Scores[0] = 99
+    c.Tokens[0] = simple.NoneToken
+    for i, _ := range c.Board.Cities {
+        if len(c.Board.Cities[i].Coellen.Spots) > 0 {
+            c.Board.Cities[i].Coellen.Spots[0].Piece = simple.Piece{}
+        }
+    }
+    if orig.Json() != want {
+        t.Errorf("Changing a Clone changed the original")
+    }
+}
+
+func BenchmarkTableJson(b *testing.B) {
+    t := cloneTestTable()
+    for i:=0;i<b.N;i++ {
+        c := simple.Table{}
+        if err := json.Unmarshal([]byte(t.Json()), &c); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkTableClone(b *testing.B) {
+    t := cloneTestTable()
+    for i:=0;i<b.N;i++ {
+        t.Clone()
+    }
+}
+
+func BenchmarkSendJson(b *testing.B) {
+    ms := cloneTestMessages()
+    for i:=0;i<b.N;i++ {
+        jsonClone(b, ms[i % len(ms)])
+    }
+}
+
+func BenchmarkSendClone(b *testing.B) {
+    ms := cloneTestMessages()
+    for i:=0;i<b.N;i++ {
+        ms[i % len(ms)].Clone()
+    }
+}
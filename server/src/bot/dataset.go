@@ -0,0 +1,174 @@
+package bot
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "local/hansa/simple"
+)
+
+// How many of a decision's plans we keep, best first (there can be hundreds).
+const decisionPlans = 50
+
+// What a Decision chose.
+const (
+    PlanDecision = "Plan" // a plan for our turn
+    BumpDecision = "Bump" // where a piece goes when we're bumped
+)
+
+// One plan a RouteBrain chose, with what it weighed, for training models
+// offline (see Manager.RecordDecisions and game.SimulationConfig.Dataset).
+type Decision struct {
+    FeatureVersion int
+    Kind string
+    Board string
+    Game int
+    Player int
+    Number int // Player's n'th decision this game, from 1
+    ActionsLeft int // 0 for bumps; it isn't our turn
+    GameTime string
+    Scores []int
+    Table []float64 // EncodeTable from Player's seat, before the plan
+    TurnState simple.TurnState // the last the server sent
+
+    // Plans[0] was played (handicaps don't always play the best).  For a
+    // bump, each is a spot and the best plan it leaves us next turn.
+    Plans []DecisionPlan
+
+    // Filled in once the game is over: every player's final place (0 is
+    // first, ties share), and Player's.
+    Places []int
+    Place int
+}
+
+type DecisionPlan struct {
+    RouteId int
+    Goal string
+    Length string
+    Value float64
+    Fitness Fitness
+    Features []float64 // EncodePlan
+    Dest simple.Location // bumps only, where the piece goes
+}
+
+// Fitness is an interface, so which one it is comes from Goal.
+func (p *DecisionPlan) UnmarshalJSON(bytes []byte) error {
+    type decisionPlan DecisionPlan
+    var d struct {
+        decisionPlan
+        Fitness json.RawMessage
+    }
+    if err := json.Unmarshal(bytes, &d); err != nil {
+        return err
+    }
+    *p = DecisionPlan(d.decisionPlan)
+    var f Fitness
+    switch d.Goal {
+        case goalNames[AwardGoal]:
+            f = &AwardPlanFitness{}
+        case goalNames[OfficeGoal]:
+            f = &OfficePlanFitness{}
+        case goalNames[PointsGoal]:
+            f = &PointsPlanFitness{}
+        case goalNames[BlockGoal]:
+            f = &BlockPlanFitness{}
+        default:
+            return nil
+    }
+    if err := json.Unmarshal(d.Fitness, f); err != nil {
+        return err
+    }
+    p.Fitness = f
+    return nil
+}
+
+// Each as a line of JSON, which is how datasets are kept.
+func WriteDecisions(w io.Writer, ds []Decision) error {
+    e := json.NewEncoder(w)
+    for _, d := range ds {
+        if err := e.Encode(d); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func ReadDecisions(r io.Reader) ([]Decision, error) {
+    ds := []Decision{}
+    d := json.NewDecoder(bufio.NewReader(r))
+    for {
+        var decision Decision
+        if err := d.Decode(&decision); err == io.EOF {
+            return ds, nil
+        } else if err != nil {
+            return ds, err
+        }
+        ds = append(ds, decision)
+    }
+}
+
+// Places by score, 0 is first and ties share a place.
+func Places(scores []int) []int {
+    r := make([]int, len(scores))
+    for i, _ := range scores {
+        for _, s := range scores {
+            if s > scores[i] {
+                r[i]++
+            }
+        }
+    }
+    return r
+}
+
+func (b *RouteBrain) decision(plans []Plan, actions int, c Context) Decision {
+    d := b.newDecision(PlanDecision, actions, c)
+    for i, p := range plans {
+        if i == decisionPlans {
+            break
+        }
+        d.Plans = append(d.Plans, newDecisionPlan(p))
+    }
+    return d
+}
+
+// candidates in the order we ranked them, so the one we chose is first.
+func (b *RouteBrain) bumpDecision(candidates []bumpCandidate, order []int, c Context) Decision {
+    d := b.newDecision(BumpDecision, 0, c)
+    for i, o := range order {
+        if i == decisionPlans {
+            break
+        }
+        p := newDecisionPlan(candidates[o].next)
+        p.Dest = candidates[o].dest
+        d.Plans = append(d.Plans, p)
+    }
+    return d
+}
+
+func (b *RouteBrain) newDecision(kind string, actions int, c Context) Decision {
+    b.decisions++
+    return Decision{
+        FeatureVersion: FeatureVersion,
+        Kind: kind,
+        Board: b.table.Board.Name,
+        Game: b.gameId,
+        Player: b.player,
+        Number: b.decisions,
+        ActionsLeft: actions,
+        GameTime: gameTimeNames[c.GameTime],
+        Scores: append([]int{}, b.scores...),
+        Table: EncodeTable(&b.table, b.scores, b.player),
+        TurnState: b.turnState,
+    }
+}
+
+func newDecisionPlan(p Plan) DecisionPlan {
+    return DecisionPlan{
+        RouteId: p.RouteId,
+        Goal: goalNames[p.Goal],
+        Length: lengthNames[p.Length],
+        Value: p.FitnessValue,
+        Fitness: p.Fitness,
+        Features: EncodePlan(p),
+    }
+}
@@ -0,0 +1,82 @@
+package bot
+
+import (
+    "math"
+    "math/rand"
+    "sort"
+    "time"
+    "local/hansa/simple"
+)
+
+// How a RouteBrain plays worse than it can.  Each plan's FitnessValue is
+// blurred by noise (as a fraction of itself, one standard deviation), and then
+// we pick uniformly among the topK plans by that blurred value.
+type handicap struct {
+    noise float64
+    topK int
+    rng *rand.Rand
+}
+
+// Beginner plays a PlaceBrain and Hard (the default) is unhandicapped, so
+// only the middle tiers are here.
+var handicaps = map[simple.Difficulty]handicap{
+    simple.EasyDifficulty: handicap{noise: 0.5, topK: 5},
+    simple.MediumDifficulty: handicap{noise: 0.2, topK: 2},
+}
+
+// Self-play (see NewSelfPlayManager) blurs a little, so games from different
+// seeds differ.
+const selfPlayNoise = 0.05
+
+func newHandicap(d simple.Difficulty) *handicap {
+    h, ok := handicaps[d]
+    if !ok {
+        return nil
+    }
+    h.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+    return &h
+}
+
+// Reorders plans (sorted best first) so that plans[0] is the one to play.  A
+// nil handicap leaves them alone.
+func (h *handicap) choose(plans []Plan) {
+    values := make([]float64, len(plans))
+    for i, p := range plans {
+        values[i] = p.FitnessValue
+    }
+    sorted := make([]Plan, len(plans))
+    for i, o := range h.rank(values) {
+        sorted[i] = plans[o]
+    }
+    copy(plans, sorted)
+}
+
+// Given values sorted best first, returns their indexes in the order we
+// should prefer them.
+func (h *handicap) rank(values []float64) []int {
+    order := make([]int, len(values))
+    for i, _ := range order {
+        order[i] = i
+    }
+    if h == nil || len(values) < 2 {
+        return order
+    }
+    if h.noise > 0 {
+        keys := make([]float64, len(values))
+        for i, v := range values {
+            keys[i] = v + math.Abs(v)*h.noise*h.rng.NormFloat64()
+        }
+        sort.SliceStable(order, func(i, j int) bool {
+            return keys[order[i]] > keys[order[j]]
+        })
+    }
+    if h.topK > 1 {
+        k := h.topK
+        if k > len(order) {
+            k = len(order)
+        }
+        j := h.rng.Intn(k)
+        order[0], order[j] = order[j], order[0]
+    }
+    return order
+}
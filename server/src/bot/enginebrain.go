@@ -0,0 +1,279 @@
+package bot
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os/exec"
+    "time"
+    "local/hansa/log"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// The engine protocol lets a local executable play a seat over stdio, using
+// the same payloads as the websocket API:
+//
+// * Every message.Server the bot receives is written to the engine's stdin as
+//   one line of JSON.
+// * The engine answers every line with zero or more message.Client lines (each
+//   one JSON object) and then a line with CType 0 ({"CType":0}) meaning done.
+// * The whole answer must arrive within engineMoveTimeout.
+// * Anything on stderr is logged.
+//
+// If the engine crashes, hangs, or talks garbage it is killed and restarted
+// with a NotifyFullGame of the current state.  Once it has been restarted
+// engineMaxRestarts times we stop trying and forfeit every turn for it.
+const engineMoveTimeout = 10 * time.Second
+const engineMaxRestarts = 3
+
+// Lines longer than this (a NotifyFullGame is ~50KB) are a protocol error.
+const engineMaxLine = 1024 * 1024
+
+// Sent as NotifyFullGameData.Status; this is game.Running, which we can't
+// import.
+const engineRunningStatus = 2
+
+type EngineBrain struct {
+    identity simple.Identity
+    gameId int
+    path string
+    args []string
+
+    cmd *exec.Cmd
+    stdin io.WriteCloser
+    lines chan string
+    done chan struct{} // closed when we stop the engine
+    restarts int
+    dead bool
+
+    // What we know of the game, so we can resync a restarted engine and
+    // forfeit for one that's gone.
+    player int
+    table simple.Table
+    scores []int
+    turnState simple.TurnState
+}
+
+func (b *EngineBrain) handleStartGame(d message.NotifyStartGameData) {
+    b.table = d.Table
+    b.scores = []int{}
+    for i, pb := range d.Table.PlayerBoards {
+        if pb.Identity == b.identity {
+            b.player = i
+        }
+        b.scores = append(b.scores, 0)
+    }
+    b.turnState = simple.NoneTurnState
+    // There's nothing to do in response to a start, so ignore any answer.
+    b.handle(message.NotifyStartGame, d)
+}
+
+func (b *EngineBrain) handleFullGame(d message.NotifyFullGameData) []message.Client {
+    b.table = d.Table
+    b.scores = append([]int{}, d.Scores...)
+    b.turnState = d.TurnState
+    for i, pb := range d.Table.PlayerBoards {
+        if pb.Identity == b.identity {
+            b.player = i
+        }
+    }
+    return b.handle(message.NotifyFullGame, d)
+}
+
+func (b *EngineBrain) handleNotifySubaction(d message.NotifySubactionData) []message.Client {
+    for i, s := range d.Scores {
+        b.scores[i] += s
+    }
+    b.table.ApplySubaction(d.Subaction, b.identity)
+    b.turnState = d.TurnState
+    return b.handle(message.NotifySubaction, d)
+}
+
+func (b *EngineBrain) handleNotifyNextTurn(d message.NotifyNextTurnData) []message.Client {
+    b.turnState = d.TurnState
+    return b.handle(message.NotifyNextTurn, d)
+}
+
+func (b *EngineBrain) handleNotifySubactionError(d message.NotifySubactionErrorData) []message.Client {
+    b.errorf("Engine submitted a bad Subaction: %v", d)
+    return b.handle(message.NotifySubactionError, d)
+}
+
+func (b *EngineBrain) handleNotifyEndBump(d message.NotifyEndBumpData) []message.Client {
+    b.turnState = d.TurnState
+    return b.handle(message.NotifyEndBump, d)
+}
+
+// Passes one message to the engine and returns its answer, restarting or
+// forfeiting for it as needed.
+func (b *EngineBrain) handle(t message.SType, d interface{}) []message.Client {
+    if b.dead {
+        return b.forfeit()
+    }
+    if b.cmd == nil {
+        if err := b.start(); err != nil {
+            b.errorf("Unable to start engine: %s", err)
+            return b.restart()
+        }
+    }
+    r, err := b.exchange(message.Server{
+        SType: t,
+        Time: time.Now(),
+        Data: d,
+    })
+    if err != nil {
+        b.errorf("Engine failed on %s: %s", t, err)
+        return b.restart()
+    }
+    return r
+}
+
+// Kills the engine and starts it again from a NotifyFullGame, until we run
+// out of restarts.
+func (b *EngineBrain) restart() []message.Client {
+    for {
+        b.stop()
+        if b.restarts >= engineMaxRestarts {
+            b.errorf("Engine restarted %d times, forfeiting the rest of the game", b.restarts)
+            b.dead = true
+            return b.forfeit()
+        }
+        b.restarts++
+        b.infof("Restarting engine (%d/%d)", b.restarts, engineMaxRestarts)
+        if err := b.start(); err != nil {
+            b.errorf("Unable to start engine: %s", err)
+            continue
+        }
+        r, err := b.exchange(message.Server{
+            SType: message.NotifyFullGame,
+            Time: time.Now(),
+            Data: b.fullGame(),
+        })
+        if err != nil {
+            b.errorf("Engine failed on resync: %s", err)
+            continue
+        }
+        return r
+    }
+}
+
+func (b *EngineBrain) start() error {
+    cmd := exec.Command(b.path, b.args...)
+    stdin, err := cmd.StdinPipe()
+    if err != nil {
+        return err
+    }
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        return err
+    }
+    stderr, err := cmd.StderrPipe()
+    if err != nil {
+        return err
+    }
+    if err = cmd.Start(); err != nil {
+        return err
+    }
+    b.debugf("Started engine %s (pid %d)", b.path, cmd.Process.Pid)
+
+    // Nobody reads lines once the engine is stopped, so stop sending then.
+    lines := make(chan string, 10)
+    done := make(chan struct{})
+    go func() {
+        scanner := bufio.NewScanner(stdout)
+        scanner.Buffer(make([]byte, 64*1024), engineMaxLine)
+        for scanner.Scan() {
+            select {
+                case lines <- scanner.Text():
+                case <-done:
+                    return
+            }
+        }
+        close(lines)
+    }()
+    go func() {
+        scanner := bufio.NewScanner(stderr)
+        for scanner.Scan() {
+            b.debugf("Engine stderr: %s", scanner.Text())
+        }
+    }()
+
+    b.cmd = cmd
+    b.stdin = stdin
+    b.lines = lines
+    b.done = done
+    return nil
+}
+
+func (b *EngineBrain) stop() {
+    if b.cmd == nil {
+        return
+    }
+    close(b.done)
+    b.stdin.Close()
+    b.cmd.Process.Kill()
+    cmd := b.cmd
+    go cmd.Wait()
+    b.cmd = nil
+}
+
+func (b *EngineBrain) exchange(m message.Server) ([]message.Client, error) {
+    bytes, err := json.Marshal(m)
+    if err != nil {
+        return nil, err
+    }
+    if _, err = b.stdin.Write(append(bytes, '\n')); err != nil {
+        return nil, err
+    }
+
+    r := []message.Client{}
+    timer := time.NewTimer(engineMoveTimeout)
+    defer timer.Stop()
+    for {
+        select {
+            case line, ok := <-b.lines:
+                if !ok {
+                    return nil, errors.New("engine exited")
+                }
+                c, err := message.UnmarshalClient([]byte(line))
+                if err != nil {
+                    return nil, fmt.Errorf("bad line '%s': %s", line, err)
+                }
+                if c.CType == message.CTypeNone {
+                    return r, nil
+                }
+                r = append(r, c)
+            case <-timer.C:
+                return nil, fmt.Errorf("no answer within %s", engineMoveTimeout)
+        }
+    }
+}
+
+func (b *EngineBrain) fullGame() message.NotifyFullGameData {
+    return message.NotifyFullGameData{
+        Status: engineRunningStatus,
+        Table: b.table,
+        TurnState: b.turnState,
+        Scores: append([]int{}, b.scores...),
+    }
+}
+
+func (b *EngineBrain) forfeit() []message.Client {
+    b.debugf("Forfeiting for the engine")
+    return forfeit(&b.table, b.turnState, b.player)
+}
+
+func (b *EngineBrain) debugf(msg string, fargs ...interface{}) {
+    log.Debug(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
+}
+
+func (b *EngineBrain) infof(msg string, fargs ...interface{}) {
+    log.Info(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
+}
+
+func (b *EngineBrain) errorf(msg string, fargs ...interface{}) {
+    log.Error(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
+}
@@ -0,0 +1,152 @@
+package bot
+
+import (
+    "fmt"
+    "local/hansa/simple"
+)
+
+// A player's final score if the game ended now, scored the way the game does
+// at the end (see Game.endgameScoring), except that we can't know who has
+// resigned.
+type Projection struct {
+    Game int
+    Board int // 4 for each emptied Actions, Books, Priviledge and Bags track
+    Coellen int
+    Control int // 2 for each city
+    Network int // times keys
+    Total int
+
+    // 0 is first; players with the same Total share a place.
+    Place int
+}
+
+// Projects everyone's final score from any table and the game scores so far.
+func ProjectScores(t *simple.Table, scores []int) []Projection {
+    r := make([]Projection, len(t.PlayerBoards))
+    player := map[simple.PlayerColor]int{}
+    for i, pb := range t.PlayerBoards {
+        player[pb.Color] = i
+        if i < len(scores) {
+            r[i].Game = scores[i]
+        }
+        for _, n := range []int{pb.GetActionCubes(), pb.GetBookDiscs(), pb.GetPriviledgeCubes(), pb.GetBagCubes()} {
+            if n == 0 {
+                r[i].Board += 4
+            }
+        }
+        r[i].Network = t.Board.GetNetworkScore(pb.Color) * pb.GetKeys()
+    }
+    for _, c := range t.Board.Cities {
+        if p, ok := player[c.GetControl()]; ok {
+            r[p].Control += 2
+        }
+        for _, s := range c.Coellen.Spots {
+            if p, ok := player[s.Piece.PlayerColor]; ok && s.Piece != (simple.Piece{}) {
+                r[p].Coellen += s.Points
+            }
+        }
+    }
+    totals := []int{}
+    for i, _ := range r {
+        r[i].Total = r[i].Game + r[i].Board + r[i].Coellen + r[i].Control + r[i].Network
+        totals = append(totals, r[i].Total)
+    }
+    for i, place := range Places(totals) {
+        r[i].Place = place
+    }
+    return r
+}
+
+// How a plan changes our projected finish, used instead of its raw points in
+// the LateGame.  Margin is our Total less the best opponent's.
+type Standing struct {
+    PlaceDelta int // places gained
+    MarginDelta int
+}
+
+// Each place gained multiplies by Place (each lost divides), and each point
+// of margin by MyPoints (gained) or OthersPoints (lost).
+func (s *Standing) apply(w Weights, calc string, value float64) (string, float64) {
+    if s.PlaceDelta != 0 {
+        f := 1.0
+        for i:=0;i<s.PlaceDelta;i++ {
+            f *= w.Place
+        }
+        for i:=0;i>s.PlaceDelta;i-- {
+            f /= w.Place
+        }
+        calc = fmt.Sprintf("%s * %.2f (Place[%+d])", calc, f, s.PlaceDelta)
+        value *= f
+    }
+    for i:=0;i<s.MarginDelta;i++ {
+        calc = fmt.Sprintf("%s * %.2f (MyPoints)", calc, w.MyPoints)
+        value *= w.MyPoints
+    }
+    for i:=0;i>s.MarginDelta;i-- {
+        calc = fmt.Sprintf("%s * %.2f (OthersPoints)", calc, w.OthersPoints)
+        value *= w.OthersPoints
+    }
+    return calc, value
+}
+
+func margin(ps []Projection, p int) int {
+    best := 0
+    found := false
+    for i, x := range ps {
+        if i != p && (!found || x.Total > best) {
+            best = x.Total
+            found = true
+        }
+    }
+    return ps[p].Total - best
+}
+
+// Sets the Standing of every plan that clears a route (and so scores), and
+// re-evaluates them.  Plans are played on b.table up to their first bump and
+// undone, so what a plan does after bumping only counts as the points for
+// clearing (if it clears this turn).
+func (b *RouteBrain) projectPlans(plans []Plan, w Weights) {
+    before := ProjectScores(&b.table, b.scores)
+    for i, p := range plans {
+        var standing **Standing
+        switch f := p.Fitness.(type) {
+            case *PointsPlanFitness:
+                standing = &f.Standing
+            case *AwardPlanFitness:
+                standing = &f.Standing
+            case *OfficePlanFitness:
+                standing = &f.Standing
+            default:
+                continue
+        }
+
+        // Who gets points for clearing, before the route is gone.
+        scores := append([]int{}, b.scores...)
+        route := b.table.Board.Routes[p.RouteId]
+        for _, id := range []int{route.LeftCityId, route.RightCityId} {
+            if p.Length != ShortPlan && p.Length != FullPlan {
+                break
+            }
+            if control := b.table.Board.Cities[id].GetControl(); control != simple.NonePlayerColor {
+                if x := b.playerOf(control); x >= 0 && x < len(scores) {
+                    scores[x]++
+                }
+            }
+        }
+
+        applied := p.Subactions
+        if len(p.Bumps) > 0 {
+            applied = applied[0:p.Bumps[0]+1]
+        }
+        b.table.ApplySubactions(applied, b.identity)
+        after := ProjectScores(&b.table, scores)
+        b.table.UndoSubactions(applied)
+
+        *standing = &Standing{
+            PlaceDelta: before[b.player].Place - after[b.player].Place,
+            MarginDelta: margin(after, b.player) - margin(before, b.player),
+        }
+        plans[i].FitnessValue = p.Fitness.Value(w)
+        plans[i].FitnessDescription = p.Fitness.Calculation(w)
+    }
+}
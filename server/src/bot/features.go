@@ -0,0 +1,230 @@
+package bot
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "math"
+    "local/hansa/simple"
+)
+
+// Stable numeric encodings of a table and of plans, for models trained
+// offline on self-play (see Decision).  A table is seen from the deciding
+// player's seat: seat 0 is them and the rest follow in turn order, padded out
+// to featureSeats, so the same position encodes the same wherever they sat.
+// Bump FeatureVersion whenever an encoding changes meaning.
+const FeatureVersion = 1
+
+const featureSeats = 5
+
+// What each feature is, in order, for one board (tables on different boards
+// have different lengths).
+type FeatureNames struct {
+    Version int
+    Board string
+    Table []string
+    Plan []string
+}
+
+func NewFeatureNames(t *simple.Table) FeatureNames {
+    return FeatureNames{
+        Version: FeatureVersion,
+        Board: t.Board.Name,
+        Table: encodeTable(t, nil, 0).names,
+        Plan: encodePlan(Plan{}).names,
+    }
+}
+
+// FeatureNames by Board, which is how a dataset's are kept beside it.
+func WriteFeatureNames(path string, fs map[string]FeatureNames) error {
+    bytes, err := json.MarshalIndent(fs, "", "  ")
+    if err != nil {
+        return err
+    }
+    return ioutil.WriteFile(path, bytes, 0644)
+}
+
+func LoadFeatureNames(path string) (map[string]FeatureNames, error) {
+    fs := map[string]FeatureNames{}
+    bytes, err := ioutil.ReadFile(path)
+    if err != nil {
+        return fs, err
+    }
+    if err := json.Unmarshal(bytes, &fs); err != nil {
+        return fs, fmt.Errorf("%s: %s", path, err)
+    }
+    return fs, nil
+}
+
+type features struct {
+    names []string
+    values []float64
+}
+
+func (f *features) add(name string, v float64) {
+    f.names = append(f.names, name)
+    f.values = append(f.values, v)
+}
+
+func (f *features) flag(name string, b bool) {
+    v := 0.0
+    if b {
+        v = 1
+    }
+    f.add(name, v)
+}
+
+// One flag per seat, set for whoever owns piece (none for an empty one).
+func (f *features) seats(prefix string, piece simple.Piece, seat map[simple.PlayerColor]int) {
+    s, ok := seat[piece.PlayerColor]
+    for i:=0;i<featureSeats;i++ {
+        f.flag(fmt.Sprintf("%s.seat%d", prefix, i), ok && piece != (simple.Piece{}) && s == i)
+    }
+}
+
+func EncodeTable(t *simple.Table, scores []int, player int) []float64 {
+    return encodeTable(t, scores, player).values
+}
+
+func encodeTable(t *simple.Table, scores []int, player int) features {
+    f := features{}
+    n := len(t.PlayerBoards)
+    seat := map[simple.PlayerColor]int{}
+    for i:=0;i<n;i++ {
+        seat[t.PlayerBoards[(player+i)%n].Color] = i
+    }
+
+    for i:=0;i<featureSeats;i++ {
+        name := func(s string) string {
+            return fmt.Sprintf("seat%d.%s", i, s)
+        }
+        if i >= n {
+            for _, s := range []string{"present", "score", "actions", "priviledge", "books", "bags", "keys", "stock", "supply", "network", "bonusRoute"} {
+                f.add(name(s), 0)
+            }
+            continue
+        }
+        p := (player+i)%n
+        pb := t.PlayerBoards[p]
+        score := 0
+        if p < len(scores) {
+            score = scores[p]
+        }
+        f.flag(name("present"), true)
+        f.add(name("score"), float64(score))
+        f.add(name("actions"), float64(pb.GetActions()))
+        f.add(name("priviledge"), float64(pb.GetPriviledge()))
+        f.add(name("books"), float64(pb.GetBooks()))
+        f.add(name("bags"), float64(pb.GetBags()))
+        f.add(name("keys"), float64(pb.GetKeys()))
+        f.add(name("stock"), float64(countPieces(pb.Stock)))
+        f.add(name("supply"), float64(countPieces(pb.Supply)))
+        f.add(name("network"), float64(t.Board.GetNetworkScore(pb.Color)))
+        f.flag(name("bonusRoute"), t.Board.GetBonusRouteCompleted(pb.Color))
+    }
+
+    f.add("filledCities", float64(t.Board.GetFilledCityCount()))
+    for _, r := range t.Board.Routes {
+        for i, s := range r.Spots {
+            prefix := fmt.Sprintf("route%d.spot%d", r.Id, i)
+            f.seats(prefix, s, seat)
+            f.flag(prefix+".disc", s != (simple.Piece{}) && s.Shape == simple.DiscShape)
+        }
+    }
+    for _, c := range t.Board.Cities {
+        for i, o := range c.Offices {
+            f.seats(fmt.Sprintf("city%d.office%d", c.Id, i), o.Piece, seat)
+        }
+        f.seats(fmt.Sprintf("city%d.control", c.Id), simple.Piece{PlayerColor: c.GetControl()}, seat)
+        for i, s := range c.Coellen.Spots {
+            f.seats(fmt.Sprintf("city%d.coellen%d", c.Id, i), s.Piece, seat)
+        }
+    }
+    return f
+}
+
+func countPieces(ps []simple.Piece) int {
+    r := 0
+    for _, p := range ps {
+        if p != (simple.Piece{}) {
+            r++
+        }
+    }
+    return r
+}
+
+// A plan by its parts rather than its FitnessValue, so a model can weigh them
+// itself.  Fitness fields a plan's Goal doesn't have are 0.
+func EncodePlan(p Plan) []float64 {
+    return encodePlan(p).values
+}
+
+func encodePlan(p Plan) features {
+    f := features{}
+    for _, g := range allGoals {
+        f.flag("goal."+goalNames[g], p.Goal == g)
+    }
+    for _, l := range []PlanLength{ShortPlan, FullPlan, AlmostPlan, LongPlan} {
+        f.flag("length."+lengthNames[l], p.Length == l)
+    }
+    f.add("actions", float64(p.Actions))
+    f.add("leftoverMoves", float64(p.LeftoverMoves))
+    f.add("bumps", float64(len(p.Bumps)))
+    f.add("logValue", math.Log1p(math.Max(0, p.FitnessValue)))
+
+    var bumps []BumpFitnessInfo
+    var standing *Standing
+    x := map[string]float64{}
+    flag := func(name string, b bool) {
+        if b {
+            x[name] = 1
+        }
+    }
+    switch a := p.Fitness.(type) {
+        case *PointsPlanFitness:
+            bumps, standing = a.BumpInfos, a.Standing
+            x["movedScore"] = a.MovedScore
+            x["myPoints"] = float64(a.MyPoints)
+            x["othersPoints"] = float64(a.OthersPoints)
+        case *AwardPlanFitness:
+            bumps, standing = a.BumpInfos, a.Standing
+            x["movedScore"] = a.MovedScore
+            x["myPoints"] = float64(a.MyPoints)
+            x["othersPoints"] = float64(a.OthersPoints)
+            x["awardsLeft"] = float64(a.AwardsLeft)
+        case *OfficePlanFitness:
+            bumps, standing = a.BumpInfos, a.Standing
+            x["movedScore"] = a.MovedScore
+            x["myPoints"] = float64(a.MyPoints)
+            x["othersPoints"] = float64(a.OthersPoints)
+            flag("firstOffice", a.FirstOffice)
+            flag("awardOffice", a.AwardOffice)
+            flag("nonControlOffice", a.NonControlOffice)
+            flag("discOffice", a.DiscOffice)
+            x["networkDelta"] = float64(a.NetworkDelta)
+        case *BlockPlanFitness:
+            x["movedScore"] = a.MovedScore
+            x["discs"] = float64(a.Discs)
+            x["stockAndSupply"] = float64(a.StockAndSupply)
+            x["opponentDesire"] = a.OpponentDesire
+            x["denied"] = a.Denied
+            flag("doublePiece", a.DoublePiece)
+            flag("doublePlayer", a.DoublePlayer)
+    }
+    for _, b := range bumps {
+        flag("bumpDisc", b.Disc)
+        x["bumpBags"] += float64(b.Bags)
+        x["bumpDenied"] += b.Denied
+    }
+    if standing != nil {
+        x["placeDelta"] = float64(standing.PlaceDelta)
+        x["marginDelta"] = float64(standing.MarginDelta)
+    }
+    for _, name := range []string{"movedScore", "myPoints", "othersPoints", "awardsLeft",
+            "firstOffice", "awardOffice", "nonControlOffice", "discOffice", "networkDelta",
+            "discs", "stockAndSupply", "opponentDesire", "denied", "doublePiece", "doublePlayer",
+            "bumpDisc", "bumpBags", "bumpDenied", "placeDelta", "marginDelta"} {
+        f.add(name, x[name])
+    }
+    return f
+}
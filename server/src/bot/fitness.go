@@ -17,6 +17,7 @@ type PointsPlanFitness struct {
     MovedScore float64
     MyPoints int
     OthersPoints int
+    Standing *Standing // LateGame only, replaces the points
 }
 
 type AwardPlanFitness struct {
@@ -27,6 +28,7 @@ type AwardPlanFitness struct {
     MovedScore float64
     MyPoints int
     OthersPoints int
+    Standing *Standing // LateGame only, replaces the points
 }
 
 type OfficePlanFitness struct {
@@ -40,6 +42,7 @@ type OfficePlanFitness struct {
     MovedScore float64
     MyPoints int
     OthersPoints int
+    Standing *Standing // LateGame only, replaces the points
 }
 
 type BlockPlanFitness struct {
@@ -48,6 +51,7 @@ type BlockPlanFitness struct {
     StockAndSupply int // Capped at of 10
     MovedScore float64
     OpponentDesire float64
+    Denied float64 // what it stops opponents doing next turn (see threat.go)
     DoublePiece bool
     DoublePlayer bool
 }
@@ -56,6 +60,7 @@ type BumpFitnessInfo struct {
     Disc bool
     Bags int
     StockAndSupply int // Capped at of 10
+    Denied float64 // what it stops the bumped player doing next turn
 }
 
 func (a *OfficePlanFitness) Calculation(w Weights) string {
@@ -105,15 +110,23 @@ func (a *OfficePlanFitness) calculate(w Weights) (string, float64) {
         calc = fmt.Sprintf("%s * %.2f (Bump[%d][%d])", calc,
             w.Bump[b.Bags][b.StockAndSupply], b.Bags, b.StockAndSupply)
         value *= w.Bump[b.Bags][b.StockAndSupply]
+        if b.Denied > 0 {
+            calc = fmt.Sprintf("%s * %.2f (Denial[%.2f])", calc, 1+w.Denial*b.Denied, b.Denied)
+            value *= 1+w.Denial*b.Denied
+        }
     }
 
-    for i:=0;i<a.MyPoints;i++ {
-        calc = fmt.Sprintf("%s * %.2f (MyPoints)", calc, w.MyPoints)
-        value *= w.MyPoints
-    }
-    for i:=0;i<a.OthersPoints;i++ {
-        calc = fmt.Sprintf("%s * %.2f (OthersPoints)", calc, w.OthersPoints)
-        value *= w.OthersPoints
+    if a.Standing != nil {
+        calc, value = a.Standing.apply(w, calc, value)
+    } else {
+        for i:=0;i<a.MyPoints;i++ {
+            calc = fmt.Sprintf("%s * %.2f (MyPoints)", calc, w.MyPoints)
+            value *= w.MyPoints
+        }
+        for i:=0;i<a.OthersPoints;i++ {
+            calc = fmt.Sprintf("%s * %.2f (OthersPoints)", calc, w.OthersPoints)
+            value *= w.OthersPoints
+        }
     }
 
     if a.MovedScore > 0.0001 || a.MovedScore < -0.0001 {
@@ -166,15 +179,23 @@ func (a *AwardPlanFitness) calculate(w Weights) (string, float64) {
         calc = fmt.Sprintf("%s * %.2f (Bump[%d][%d])", calc,
             w.Bump[b.Bags][b.StockAndSupply], b.Bags, b.StockAndSupply)
         value *= w.Bump[b.Bags][b.StockAndSupply]
+        if b.Denied > 0 {
+            calc = fmt.Sprintf("%s * %.2f (Denial[%.2f])", calc, 1+w.Denial*b.Denied, b.Denied)
+            value *= 1+w.Denial*b.Denied
+        }
     }
 
-    for i:=0;i<a.MyPoints;i++ {
-        calc = fmt.Sprintf("%s * %.2f (MyPoints)", calc, w.MyPoints)
-        value *= w.MyPoints
-    }
-    for i:=0;i<a.OthersPoints;i++ {
-        calc = fmt.Sprintf("%s * %.2f (OthersPoints)", calc, w.OthersPoints)
-        value *= w.OthersPoints
+    if a.Standing != nil {
+        calc, value = a.Standing.apply(w, calc, value)
+    } else {
+        for i:=0;i<a.MyPoints;i++ {
+            calc = fmt.Sprintf("%s * %.2f (MyPoints)", calc, w.MyPoints)
+            value *= w.MyPoints
+        }
+        for i:=0;i<a.OthersPoints;i++ {
+            calc = fmt.Sprintf("%s * %.2f (OthersPoints)", calc, w.OthersPoints)
+            value *= w.OthersPoints
+        }
     }
 
     if a.MovedScore > 0.0001 || a.MovedScore < -0.0001 {
@@ -223,15 +244,23 @@ func (a *PointsPlanFitness) calculate(w Weights) (string, float64) {
         calc = fmt.Sprintf("%s * %.2f (Bump[%d][%d])", calc,
             w.Bump[b.Bags][b.StockAndSupply], b.Bags, b.StockAndSupply)
         value *= w.Bump[b.Bags][b.StockAndSupply]
+        if b.Denied > 0 {
+            calc = fmt.Sprintf("%s * %.2f (Denial[%.2f])", calc, 1+w.Denial*b.Denied, b.Denied)
+            value *= 1+w.Denial*b.Denied
+        }
     }
 
-    for i:=0;i<a.MyPoints;i++ {
-        calc = fmt.Sprintf("%s * %.2f (MyPoints)", calc, w.MyPoints)
-        value *= w.MyPoints
-    }
-    for i:=0;i<a.OthersPoints;i++ {
-        calc = fmt.Sprintf("%s * %.2f (OthersPoints)", calc, w.OthersPoints)
-        value *= w.OthersPoints
+    if a.Standing != nil {
+        calc, value = a.Standing.apply(w, calc, value)
+    } else {
+        for i:=0;i<a.MyPoints;i++ {
+            calc = fmt.Sprintf("%s * %.2f (MyPoints)", calc, w.MyPoints)
+            value *= w.MyPoints
+        }
+        for i:=0;i<a.OthersPoints;i++ {
+            calc = fmt.Sprintf("%s * %.2f (OthersPoints)", calc, w.OthersPoints)
+            value *= w.OthersPoints
+        }
     }
 
     if a.MovedScore > 0.0001 || a.MovedScore < -0.0001 {
@@ -275,6 +304,11 @@ func (a *BlockPlanFitness) calculate(w Weights) (string, float64) {
     calc = fmt.Sprintf("%s * %.2f (OpponentDesire)", calc, a.OpponentDesire)
     value *= a.OpponentDesire
 
+    if a.Denied > 0 {
+        calc = fmt.Sprintf("%s * %.2f (Denial[%.2f])", calc, 1+w.Denial*a.Denied, a.Denied)
+        value *= 1+w.Denial*a.Denied
+    }
+
     if a.DoublePiece {
         calc = fmt.Sprintf("%s * %.2f (DoublePiece)", calc, w.DoublePieceBlock)
         value *= w.DoublePieceBlock
@@ -1,17 +1,183 @@
 package bot
 
 import (
+    "errors"
+    "hash/fnv"
+    "math/rand"
+    "runtime"
+    "sort"
+    "sync"
+    "local/hansa/log"
     "local/hansa/message"
     "local/hansa/simple"
 )
 
-type Manager struct {}
+type Manager struct {
+    engines map[string]engine
+    models map[string]model
+    mcts MCTSConfig
+    planWorkers int
+
+    // Weight files by bot id (see weightfile.go), swapped whole on reload;
+    // running bots keep the WeightSet they started with.
+    weightsLock sync.RWMutex
+    weightsDir string
+    weights map[string]WeightFile
+
+    // For simulations (see NewRandomManager and NewSelfPlayManager).
+    random bool
+    selfPlay bool
+    silent bool
+    seed int64
+    recovered func(i simple.Identity, r interface{})
+    record func(Decision)
+    selfPlayModel *Model
+}
+
+// A local executable speaking the engine protocol (see enginebrain.go).
+type engine struct {
+    identity simple.Identity
+    path string
+    args []string
+}
 
 func NewManager() *Manager {
-    return &Manager{}
+    return &Manager{
+        engines: map[string]engine{},
+        models: map[string]model{},
+        mcts: DefaultMCTSConfig,
+        planWorkers: runtime.NumCPU(),
+        weights: map[string]WeightFile{},
+    }
+}
+
+// Every bot this Manager makes plays uniformly random legal moves, instantly,
+// from seed (and its Id), to fuzz the game.  Their panics go to recovered.
+func NewRandomManager(seed int64, recovered func(i simple.Identity, r interface{})) *Manager {
+    m := NewManager()
+    m.random = true
+    m.seed = seed
+    m.recovered = recovered
+    return m
+}
+
+// Every bot this Manager makes is a RouteBrain playing instantly, with
+// selfPlayNoise from seed (and its Id) so games differ, and hands each plan
+// it chooses to record (see Decision).  Their panics go to recovered.
+func NewSelfPlayManager(seed int64, recovered func(i simple.Identity, r interface{}), record func(Decision)) *Manager {
+    m := NewManager()
+    m.selfPlay = true
+    m.seed = seed
+    m.recovered = recovered
+    m.record = record
+    return m
+}
+
+// Every bot a self-play Manager makes plays the Model at path instead (see
+// ModelBrain), so a trained model can play itself.  Like RegisterEngine, only
+// call this before the Manager is shared.
+func (m *Manager) SelfPlayModel(path string) error {
+    x, err := LoadModel(path)
+    if err != nil {
+        return err
+    }
+    m.selfPlayModel = &x
+    return nil
+}
+
+// Every bot this Manager makes never answers anything, so a replay (see
+// game.Replay) can play the moves itself.
+func NewSilentManager() *Manager {
+    m := NewManager()
+    m.silent = true
+    return m
+}
+
+func seedOffset(id string) int64 {
+    h := fnv.New64a()
+    h.Write([]byte(id))
+    return int64(h.Sum64())
+}
+
+// How Expert bots search.  Like RegisterEngine, only call this before the
+// Manager is shared.
+func (m *Manager) ConfigureMCTS(c MCTSConfig) {
+    m.mcts = c
+}
+
+// How many goroutines each of our bots generates plans on.  Like
+// RegisterEngine, only call this before the Manager is shared.
+func (m *Manager) ConfigurePlanWorkers(n int) {
+    m.planWorkers = n
+}
+
+// Only call this before the Manager is shared (it isn't locked).  Engine ids
+// start with E.
+func (m *Manager) RegisterEngine(id string, name string, path string, args ...string) {
+    m.engines[id] = engine{
+        identity: simple.NewBotIdentity(id, name),
+        path: path,
+        args: args,
+    }
+}
+
+// A Model a ModelBrain plays (see modelbrain.go).
+type model struct {
+    identity simple.Identity
+    model Model
+}
+
+// Like RegisterEngine, only call this before the Manager is shared.  Model
+// ids start with M.
+func (m *Manager) RegisterModel(id string, name string, path string) error {
+    x, err := LoadModel(path)
+    if err != nil {
+        return err
+    }
+    m.models[id] = model{identity: simple.NewBotIdentity(id, name), model: x}
+    return nil
+}
+
+// Loads weight files from dir, and remembers it for ReloadWeights.  On error
+// the weights in use are kept.
+func (m *Manager) LoadWeights(dir string) error {
+    weights, err := LoadWeightFiles(dir)
+    if err != nil {
+        return err
+    }
+    m.weightsLock.Lock()
+    defer m.weightsLock.Unlock()
+    m.weightsDir = dir
+    m.weights = weights
+    for id, f := range weights {
+        log.Info("Bot %s plays weights %s", id, f)
+    }
+    return nil
+}
+
+// Safe to call while games are running; new bots get the new weights.
+func (m *Manager) ReloadWeights() error {
+    m.weightsLock.RLock()
+    dir := m.weightsDir
+    m.weightsLock.RUnlock()
+    if dir == "" {
+        return errors.New("no weights directory loaded")
+    }
+    return m.LoadWeights(dir)
+}
+
+func (m *Manager) weightsFor(id string) WeightFile {
+    m.weightsLock.RLock()
+    defer m.weightsLock.RUnlock()
+    if f, ok := m.weights[id]; ok {
+        return f
+    }
+    return builtinWeights
 }
 
-func (m *Manager) NewBot(i simple.Identity, gameId int) *Bot {
+// Difficulty only applies to our own brains; engines play how they play.
+// Bots start out watched; the game calls SetWatched when that changes.
+func (m *Manager) NewBot(i simple.Identity, gameId int, d simple.Difficulty, p simple.BotPacing) *Bot {
     /*
     var brain Brain
     if i.Id == "B5" || i.Id == "B1" {
@@ -21,14 +187,57 @@ func (m *Manager) NewBot(i simple.Identity, gameId int) *Bot {
     }
     */
 
-    brain := &RouteBrain{identity: i, gameId: gameId, weights: botWeights[i.Id]}
+    var brain Brain
+    w := m.weightsFor(i.Id)
+    weights := w.String()
+    if m.silent {
+        brain = silentBrain{}
+        weights = ""
+    } else if m.random {
+        brain = &RandomBrain{identity: i, gameId: gameId, rng: rand.New(rand.NewSource(m.seed + seedOffset(i.Id)))}
+        weights = ""
+    } else if m.selfPlay && m.selfPlayModel != nil {
+        mb := NewModelBrain(i, gameId, w.Weights, *m.selfPlayModel)
+        mb.record = m.record
+        mb.workers = m.planWorkers
+        brain = mb
+    } else if m.selfPlay {
+        h := &handicap{noise: selfPlayNoise, rng: rand.New(rand.NewSource(m.seed + seedOffset(i.Id)))}
+        brain = &RouteBrain{identity: i, gameId: gameId, weights: w.Weights, handicap: h, record: m.record, workers: m.planWorkers}
+    } else if x, ok := m.models[i.Id]; ok {
+        mb := NewModelBrain(i, gameId, w.Weights, x.model)
+        mb.workers = m.planWorkers
+        brain = mb
+        log.Info("(G%d) (Bot%s) Playing model %s", gameId, i, x.model.Name)
+    } else if e, ok := m.engines[i.Id]; ok {
+        brain = &EngineBrain{identity: i, gameId: gameId, path: e.path, args: e.args}
+        weights = ""
+    } else if d == simple.BeginnerDifficulty {
+        brain = &PlaceBrain{identity: i, gameId: gameId}
+        weights = ""
+    } else if d == simple.ExpertDifficulty {
+        mb := NewMCTSBrain(i, gameId, w.Weights, m.mcts)
+        mb.workers = m.planWorkers
+        brain = mb
+        log.Info("(G%d) (Bot%s) Playing Expert with weights %s", gameId, i, w)
+    } else {
+        brain = &RouteBrain{identity: i, gameId: gameId, weights: w.Weights, handicap: newHandicap(d), workers: m.planWorkers}
+        log.Info("(G%d) (Bot%s) Playing %s with weights %s", gameId, i, simple.DifficultyNames[d], w)
+    }
     //brain := &PlaceBrain{identity: i, gameId: gameId}
 
     b := &Bot{
-        i,
-        brain,
-        make(chan message.Server, 10),
-        make(chan message.Client, 10),
+        identity: i,
+        brain: brain,
+        inMsg: make(chan message.Server, 10),
+        outMsg: make(chan message.Client, 10),
+        pacing: p,
+        watched: 1,
+        weights: weights,
+    }
+    if m.random || m.selfPlay || m.silent {
+        b.pacing = simple.InstantBotPacing
+        b.recovered = m.recovered
     }
     go b.Run()
     return b
@@ -42,18 +251,47 @@ var botIdentities = map[string]simple.Identity{
     "B5": simple.NewBotIdentity("B5", "Cory (Bot)"),
 }
 
-var botWeights = map[string]WeightSet {
-    "B1": derekWeights,
-    "B2": caniceWeights,
-    "B3": jacobWeights,
-    "B4": bcripeWeights,
-    "B5": coryWeights,
-}
-
 func (m *Manager) GetIdentity(id string) simple.Identity {
     if b, ok := botIdentities[id]; ok {
         return b
     }
+    if e, ok := m.engines[id]; ok {
+        return e.identity
+    }
+    if x, ok := m.models[id]; ok {
+        return x.identity
+    }
     return simple.EmptyIdentity
 }
 
+// Just our own RouteBrain bots (no engines or models), sorted by Id; the ones
+// matchmaking may seat with strangers.
+func (m *Manager) BuiltinIdentities() []simple.Identity {
+    r := []simple.Identity{}
+    for _, i := range botIdentities {
+        r = append(r, i)
+    }
+    sort.Slice(r, func(i, j int) bool {
+        return r[i].Id < r[j].Id
+    })
+    return r
+}
+
+// All bots, sorted by Id.
+func (m *Manager) Identities() []simple.Identity {
+    r := []simple.Identity{}
+    for _, i := range botIdentities {
+        r = append(r, i)
+    }
+    for _, e := range m.engines {
+        r = append(r, e.identity)
+    }
+    for _, x := range m.models {
+        r = append(r, x.identity)
+    }
+    sort.Slice(r, func(i, j int) bool {
+        return r[i].Id < r[j].Id
+    })
+    return r
+}
+
@@ -0,0 +1,369 @@
+package bot
+
+import (
+    "fmt"
+    "math"
+    "math/rand"
+    "runtime"
+    "runtime/debug"
+    "strings"
+    "sync"
+    "time"
+    "local/hansa/simple"
+)
+
+// How hard an MCTSBrain thinks.  Each plan it chooses gets up to Budget of
+// wall time or Iterations rollouts (0 for no limit, but at least one must be
+// set), spread over Workers goroutines.
+type MCTSConfig struct {
+    Budget time.Duration
+    Iterations int
+    Workers int
+
+    // We search the best Candidates plans RouteBrain finds, playing out
+    // Horizon rounds after each before judging the table.
+    Candidates int
+    Horizon int
+
+    // UCB1's exploration constant (rewards are 0 to 1).
+    Exploration float64
+}
+
+var DefaultMCTSConfig = MCTSConfig{
+    Budget: 2 * time.Second,
+    Workers: runtime.NumCPU(),
+    Candidates: 8,
+    Horizon: 4,
+    Exploration: 0.7,
+}
+
+// How rollout brains play: RouteBrain, a bit noisy so rollouts differ.
+var rolloutHandicap = handicap{noise: 0.3, topK: 1}
+
+// MCTSBrain plays like a RouteBrain (it is one, for tracking the game and
+// answering bumps), but rather than trusting FitnessValue it picks among the
+// top plans by playing the game forward from each, many times over, with
+// noisy RouteBrains in every seat.  This is flat Monte Carlo with UCB1 at the
+// root: each candidate is a bandit arm, and a rollout's reward is our lead at
+// the end of it.
+type MCTSBrain struct {
+    *RouteBrain
+    config MCTSConfig
+}
+
+func NewMCTSBrain(i simple.Identity, gameId int, weights WeightSet, config MCTSConfig) *MCTSBrain {
+    b := &MCTSBrain{
+        RouteBrain: &RouteBrain{identity: i, gameId: gameId, weights: weights},
+        config: config,
+    }
+    b.RouteBrain.choose = b.search
+    return b
+}
+
+type arm struct {
+    plan Plan
+    visits int
+    reward float64
+}
+
+// Reorders plans so the one we want is plans[0].  b.table is our current
+// (hypothetical, mid-turn) table and is left alone.
+func (b *MCTSBrain) search(plans []Plan, actions int) {
+    n := b.config.Candidates
+    if n > len(plans) {
+        n = len(plans)
+    }
+    if n < 2 {
+        return
+    }
+
+    arms := make([]*arm, n)
+    for i:=0;i<n;i++ {
+        arms[i] = &arm{plan: plans[i]}
+    }
+    root := b.table.Clone()
+    scores := append([]int{}, b.scores...)
+
+    lock := sync.Mutex{}
+    total := 0
+    failed := 0
+    var firstFailure *rolloutError
+    var crash interface{}
+    deadline := time.Now().Add(b.config.Budget)
+    next := func() (int, bool) {
+        lock.Lock()
+        defer lock.Unlock()
+        if b.config.Iterations > 0 && total >= b.config.Iterations {
+            return 0, false
+        }
+        if b.config.Budget > 0 && time.Now().After(deadline) {
+            return 0, false
+        }
+        total++
+        return b.selectArm(arms, total), true
+    }
+
+    wg := sync.WaitGroup{}
+    workers := b.config.Workers
+    if workers < 1 {
+        workers = 1
+    }
+    for w:=0;w<workers;w++ {
+        wg.Add(1)
+        go func(seed int64) {
+            defer wg.Done()
+
+            // Anything but a rolloutFailure is a bug; it can't be recovered
+            // on our goroutine, so it goes back to the brain's.
+            defer func() {
+                if r := recover(); r != nil {
+                    lock.Lock()
+                    crash = fmt.Sprintf("%v\n%s", r, debug.Stack())
+                    lock.Unlock()
+                }
+            }()
+            rng := rand.New(rand.NewSource(seed))
+            for {
+                i, ok := next()
+                if !ok {
+                    return
+                }
+                reward, err := b.rollout(&root, scores, arms[i].plan, actions, rng)
+                lock.Lock()
+                if err == nil {
+                    arms[i].visits++
+                    arms[i].reward += reward
+                } else {
+                    failed++
+                    if firstFailure == nil {
+                        firstFailure = err
+                    }
+                }
+                stop := crash != nil
+                lock.Unlock()
+                if stop {
+                    return
+                }
+            }
+        }(time.Now().UnixNano() + int64(w))
+    }
+    wg.Wait()
+    if crash != nil {
+        panic(crash)
+    }
+    if failed > 0 {
+        b.errorf("%d of %d rollouts FAILED, the first: %s\n%s", failed, total, firstFailure.why, firstFailure.stack)
+    }
+
+    best := 0
+    parts := []string{}
+    for i, a := range arms {
+        if a.visits > arms[best].visits {
+            best = i
+        }
+        parts = append(parts, fmt.Sprintf("%s/%d:%.2f(%d)",
+            goalNames[a.plan.Goal], a.plan.RouteId, a.mean(), a.visits))
+    }
+    b.debugf("Searched %d rollouts (%d failed) over {%s}", total, failed, strings.Join(parts, " "))
+    if best != 0 {
+        b.debugf("Search prefers %s on route %d (%.2f) over %s on route %d (%.2f)",
+            goalNames[arms[best].plan.Goal], arms[best].plan.RouteId, arms[best].mean(),
+            goalNames[arms[0].plan.Goal], arms[0].plan.RouteId, arms[0].mean())
+    }
+    plans[0], plans[best] = plans[best], plans[0]
+}
+
+// Untried arms first, then UCB1.
+func (b *MCTSBrain) selectArm(arms []*arm, total int) int {
+    best := 0
+    bestValue := math.Inf(-1)
+    for i, a := range arms {
+        if a.visits == 0 {
+            return i
+        }
+        v := a.mean() + b.config.Exploration*math.Sqrt(math.Log(float64(total))/float64(a.visits))
+        if v > bestValue {
+            best = i
+            bestValue = v
+        }
+    }
+    return best
+}
+
+func (a *arm) mean() float64 {
+    if a.visits == 0 {
+        return 0
+    }
+    return a.reward / float64(a.visits)
+}
+
+// Our rollout rules are rougher than the game's (bumped pieces go to the
+// first valid spot, points come from plans' own estimates), so a rollout can
+// reach a table they can't play on.  It stops by panicking with this.
+type rolloutFailure string
+
+type rolloutError struct {
+    why string
+    stack string
+}
+
+// Plays plan and the rest of our turn, then Horizon rounds, on a copy of the
+// table and returns how far ahead we end up, from 0 to 1.  A rollout that
+// fails (see rolloutFailure) returns why, and is ignored.
+func (b *MCTSBrain) rollout(root *simple.Table, scores []int, plan Plan, actions int, rng *rand.Rand) (reward float64, err *rolloutError) {
+    defer func() {
+        if r := recover(); r != nil {
+            f, ok := r.(rolloutFailure)
+            if !ok {
+                panic(r)
+            }
+            err = &rolloutError{string(f), string(debug.Stack())}
+        }
+    }()
+
+    world := root.Clone()
+    scores = append([]int{}, scores...)
+    brains := []*RouteBrain{}
+    for i, pb := range world.PlayerBoards {
+        h := rolloutHandicap
+        h.rng = rng
+        brains = append(brains, &RouteBrain{
+            identity: pb.Identity,
+            gameId: b.gameId,
+            weights: b.weights,
+            handicap: &h,
+            rollout: true,
+            player: i,
+            color: pb.Color,
+        })
+    }
+
+    // Our candidate, then the rest of our turn.
+    me := brains[b.player]
+    for i, s := range plan.Subactions {
+        world.ApplySubaction(s, me.identity)
+        if len(plan.Bumps) > 0 && plan.Bumps[0] == i {
+            break
+        }
+    }
+    scores[b.player] += planPoints(plan)
+    resolveBumps(&world)
+    b.playTurn(me, &world, scores, actions - plan.Actions)
+
+    for t:=1;t<=b.config.Horizon*len(brains);t++ {
+        p := (b.player + t) % len(brains)
+        b.playTurn(brains[p], &world, scores, world.PlayerBoards[p].GetActions())
+        if gameOver(&world, scores) {
+            break
+        }
+    }
+
+    return marginReward(evaluate(&world, scores), b.player), nil
+}
+
+func (b *MCTSBrain) playTurn(rb *RouteBrain, world *simple.Table, scores []int, actions int) {
+    rb.table = *world
+    rb.scores = scores
+    for ;actions > 0; {
+        plan := rb.choosePlan(actions)
+        if plan.Actions == 0 {
+            break
+        }
+        actions -= plan.Actions
+        scores[rb.player] += planPoints(plan)
+        if len(plan.Bumps) > 0 {
+            // choosePlan stopped at the bump; the rest of the plan may not
+            // fit the table once the bump is resolved, so plan again.
+            resolveBumps(&rb.table)
+        }
+    }
+    *world = rb.table
+}
+
+// Bumped pieces go to the first valid spot (no replacements).
+func resolveBumps(t *simple.Table) {
+    for _, r := range t.Board.Routes {
+        for i, p := range r.Bumped {
+            if p == (simple.Piece{}) {
+                continue
+            }
+            l := simple.Location{
+                Type: simple.RouteLocationType,
+                Id: r.Id,
+                Index: i,
+                Subindex: 1,
+            }
+            valid := t.ValidBumps(l)
+            if len(valid) == 0 {
+                panic(rolloutFailure(fmt.Sprintf("nowhere to put the piece bumped from %v", l)))
+            }
+            t.ApplySubaction(simple.Subaction{
+                Source: l,
+                Dest: valid[0],
+                Piece: p,
+            }, simple.EmptyIdentity)
+        }
+    }
+}
+
+// Plans estimate their own points.
+func planPoints(p Plan) int {
+    switch f := p.Fitness.(type) {
+        case *PointsPlanFitness:
+            return f.MyPoints
+        case *AwardPlanFitness:
+            return f.MyPoints
+        case *OfficePlanFitness:
+            return f.MyPoints
+    }
+    return 0
+}
+
+func gameOver(t *simple.Table, scores []int) bool {
+    for _, s := range scores {
+        if s >= 20 {
+            return true
+        }
+    }
+    return t.Board.GetFilledCityCount() >= 10
+}
+
+// Starting pieces on the tracks that score 4 once emptied.
+var scoringTracks = map[simple.Award]float64{
+    simple.ActionsAward: 5,
+    simple.DiscsAward: 3,
+    simple.PriviledgeAward: 3,
+    simple.BagsAward: 3,
+}
+
+// Roughly the final score if the game ended now (see ProjectScores), with
+// partial credit for progress on tracks not yet emptied, so early rollouts
+// aren't all ties.
+func evaluate(t *simple.Table, scores []int) []float64 {
+    r := []float64{}
+    for i, p := range ProjectScores(t, scores) {
+        r = append(r, float64(p.Total))
+        for a, n := range scoringTracks {
+            if left := t.PlayerBoards[i].AwardTrackRemaining(a); left > 0 {
+                r[i] += 4 * (n - float64(left)) / n
+            }
+        }
+    }
+    return r
+}
+
+// Our lead over the best opponent, squashed to 0..1 (0.5 is even).
+const rewardScale = 5.0
+
+func marginReward(totals []float64, p int) float64 {
+    best := math.Inf(-1)
+    for i, t := range totals {
+        if i != p && t > best {
+            best = t
+        }
+    }
+    if math.IsInf(best, -1) {
+        return 1
+    }
+    return 0.5 + 0.5*math.Tanh((totals[p] - best)/rewardScale)
+}
@@ -0,0 +1,31 @@
+package bot
+
+import (
+    "testing"
+    "local/hansa/simple"
+)
+
+// With every route full a bumped piece has nowhere to go, which fails the
+// rollout rather than crashing it.
+func TestResolveBumpsNowhere(t *testing.T) {
+    table := simple.Table{
+        Board: simple.NewBase45Board(),
+        PlayerBoards: simple.NewBasePlayerBoards(),
+        Tokens: []simple.Token{},
+    }
+    c := table.PlayerBoards[0].Color
+    for r, route := range table.Board.Routes {
+        for i, _ := range route.Spots {
+            table.Board.Routes[r].Spots[i] = simple.Piece{PlayerColor: c, Shape: simple.CubeShape}
+        }
+    }
+    table.Board.Routes[0].Bumped[0] = simple.Piece{PlayerColor: c, Shape: simple.DiscShape}
+
+    defer func() {
+        r := recover()
+        if _, ok := r.(rolloutFailure); !ok {
+            t.Errorf("want a rolloutFailure, got %v", r)
+        }
+    }()
+    resolveBumps(&table)
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "local/hansa/simple"
+)
+
+// A linear model over plan features (see EncodePlan), trained offline on
+// self-play (see Decision), for example:
+//
+//     {
+//         "Name": "linear-1",
+//         "FeatureVersion": 1,
+//         "Bias": 0,
+//         "Plan": {"logValue": 1.0, "myPoints": 0.4, "bumps": -0.2}
+//     }
+//
+// Features it doesn't name are weighed 0.
+type Model struct {
+    Name string
+    FeatureVersion int
+    Bias float64
+    Plan map[string]float64
+
+    // Plan by EncodePlan's order.
+    weights []float64
+}
+
+func LoadModel(path string) (Model, error) {
+    m := Model{}
+    bytes, err := ioutil.ReadFile(path)
+    if err != nil {
+        return m, err
+    }
+    if err := json.Unmarshal(bytes, &m); err != nil {
+        return m, fmt.Errorf("%s: %s", path, err)
+    }
+    if m.FeatureVersion != FeatureVersion {
+        return m, fmt.Errorf("%s: FeatureVersion %d, but we encode %d", path, m.FeatureVersion, FeatureVersion)
+    }
+    known := map[string]bool{}
+    for _, name := range encodePlan(Plan{}).names {
+        known[name] = true
+        m.weights = append(m.weights, m.Plan[name])
+    }
+    for name, _ := range m.Plan {
+        if !known[name] {
+            return m, fmt.Errorf("%s: unknown plan feature '%s'", path, name)
+        }
+    }
+    return m, nil
+}
+
+func (m Model) score(p Plan) float64 {
+    r := m.Bias
+    for i, v := range EncodePlan(p) {
+        r += m.weights[i] * v
+    }
+    return r
+}
+
+// A RouteBrain that generates plans as usual and plays the one its Model
+// scores highest.
+type ModelBrain struct {
+    *RouteBrain
+    model Model
+}
+
+func NewModelBrain(i simple.Identity, gameId int, weights WeightSet, model Model) *ModelBrain {
+    b := &ModelBrain{
+        RouteBrain: &RouteBrain{identity: i, gameId: gameId, weights: weights},
+        model: model,
+    }
+    b.RouteBrain.choose = b.rescore
+    return b
+}
+
+// Reorders plans so the one the model likes best is plans[0].
+func (b *ModelBrain) rescore(plans []Plan, actions int) {
+    best := 0
+    bestScore := 0.0
+    for i, p := range plans {
+        if s := b.model.score(p); i == 0 || s > bestScore {
+            best = i
+            bestScore = s
+        }
+    }
+    b.debugf("Model %s prefers plan %d (%.2f) to plan 0 (%.2f)", b.model.Name, best, bestScore, b.model.score(plans[0]))
+    plans[0], plans[best] = plans[best], plans[0]
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+    "sync/atomic"
+    "time"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+const (
+    // HumanBotPacing: the first response of a decision waits humanThinkBase
+    // plus humanThinkPerPlan for every plan the brain weighed (less the time it
+    // actually took), up to humanThinkMax; the rest follow humanStep apart.
+    humanThinkBase = 500 * time.Millisecond
+    humanThinkPerPlan = 5 * time.Millisecond
+    humanThinkMax = 4 * time.Second
+    humanStep = 300 * time.Millisecond
+
+    // SpectatorBotPacing: every response waits spectatorStep, or for the
+    // score animation (renderScoreDelta in game.js) to finish.
+    spectatorStep = time.Second
+    scoreAnimation = 3 * time.Second
+)
+
+// Brains that can say how hard their last decision was, in plans weighed.
+type deliberator interface {
+    takeConsidered() int
+}
+
+// Called by the game whenever humans start or stop watching (as players or
+// observers).  Nobody needs to follow along otherwise, so we don't wait.
+func (b *Bot) SetWatched(watched bool) {
+    v := int32(0)
+    if watched {
+        v = 1
+    }
+    atomic.StoreInt32(&b.watched, v)
+}
+
+// Notes when the game is animating something we should wait for.
+func (b *Bot) observe(m message.Server) {
+    if b.pacing != simple.SpectatorBotPacing || m.SType != message.NotifySubaction {
+        return
+    }
+    for _, s := range m.Data.(message.NotifySubactionData).Scores {
+        if s != 0 {
+            b.animating = time.Now().Add(scoreAnimation)
+            return
+        }
+    }
+}
+
+// How long to wait before the i'th response to a message we started handling
+// at start.
+func (b *Bot) delay(i int, considered int, start time.Time) time.Duration {
+    if atomic.LoadInt32(&b.watched) == 0 {
+        return 0
+    }
+    switch b.pacing {
+        case simple.HumanBotPacing:
+            if i > 0 {
+                return humanStep
+            }
+            d := humanThinkBase + time.Duration(considered) * humanThinkPerPlan
+            if d > humanThinkMax {
+                d = humanThinkMax
+            }
+            return d - time.Since(start)
+        case simple.SpectatorBotPacing:
+            d := spectatorStep
+            if wait := time.Until(b.animating); wait > d {
+                d = wait
+            }
+            return d
+    }
+    return 0
+}
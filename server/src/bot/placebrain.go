@@ -16,6 +16,7 @@ type PlaceBrain struct {
     table simple.Table
     handledBump bool
     iBumped bool
+    resync
 }
 
 func (b *PlaceBrain) handleStartGame(d message.NotifyStartGameData) {
@@ -29,6 +30,36 @@ func (b *PlaceBrain) handleStartGame(d message.NotifyStartGameData) {
     }
 }
 
+func (b *PlaceBrain) handleFullGame(d message.NotifyFullGameData) []message.Client {
+    b.table = d.Table
+    b.handledBump = false
+    b.iBumped = false
+    for i, pb := range d.Table.PlayerBoards {
+        if pb.Identity == b.identity {
+            b.player = i
+            break
+        }
+    }
+
+    resynced, giveUp := b.resync.received()
+    if giveUp {
+        b.errorf("Forfeiting the turn after %d resyncs", b.resync.count)
+        return forfeit(&b.table, d.TurnState, b.player)
+    }
+    if resynced {
+        b.debugf("Resynced from the server's table")
+    }
+
+    if d.TurnState.Type == simple.Bumping && d.TurnState.BumpingPlayer == b.player {
+        b.handledBump = true
+        return b.handleBump(message.NotifySubactionData{TurnState: d.TurnState})
+    }
+    if d.TurnState.Type == simple.NoneTurnStateType {
+        return b.takeTurn(d.TurnState)
+    }
+    return forfeit(&b.table, d.TurnState, b.player)
+}
+
 // This convoluted if bool shit is because I conflated actions with what needs
 // to happen next in a single message.
 func (b *PlaceBrain) handleNotifySubaction(d message.NotifySubactionData) []message.Client {
@@ -61,22 +92,24 @@ func (b *PlaceBrain) handleNotifySubaction(d message.NotifySubactionData) []mess
 func (b *PlaceBrain) handleNotifyEndBump(d message.NotifyEndBumpData) []message.Client {
     b.handledBump = false
     if d.TurnState.Player == b.player {
-        return b.handleNotifyNextTurn(message.NotifyNextTurnData{
-            TurnState: d.TurnState,
-            Elapsed: d.Elapsed,
-        })
+        return b.takeTurn(d.TurnState)
     }
     return []message.Client{}
 }
 
 func (b *PlaceBrain) handleNotifyNextTurn(d message.NotifyNextTurnData) []message.Client {
+    b.resync.reset()
+    return b.takeTurn(d.TurnState)
+}
+
+func (b *PlaceBrain) takeTurn(ts simple.TurnState) []message.Client {
     r := []message.Client{}
-    if d.TurnState.Player != b.player {
+    if ts.Player != b.player {
         return r
     }
     b.debugf("My turn")
 
-    actions := d.TurnState.ActionsLeft
+    actions := ts.ActionsLeft
     newR, actions := b.placeOnRandomRoutes(actions)
     r = append(r, newR...)
     newR, actions = b.bags(actions)
@@ -96,8 +129,13 @@ func (b *PlaceBrain) handleNotifyNextTurn(d message.NotifyNextTurnData) []messag
     return r
 }
 
-func (b *PlaceBrain) handleNotifySubactionError(d message.NotifySubactionErrorData) {
+func (b *PlaceBrain) handleNotifySubactionError(d message.NotifySubactionErrorData) []message.Client {
     b.debugf("I submitted a bad Subaction: %v", d)
+    r := b.resync.request()
+    if r == nil {
+        b.errorf("Unable to forfeit either, waiting for the next turn")
+    }
+    return r
 }
 
 // Using at max 'actions', place cubes then discs from supply on open spots
@@ -294,3 +332,7 @@ func (b *PlaceBrain) mySupplyTarget() simple.Location {
 func (b *PlaceBrain) debugf(msg string, fargs ...interface{}) {
     log.Debug(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
 }
+
+func (b *PlaceBrain) errorf(msg string, fargs ...interface{}) {
+    log.Error(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+    "fmt"
+    "runtime/debug"
+    "sort"
+    "sync"
+    "sync/atomic"
+)
+
+// One plan to generate (see generatePlan).
+type planJob struct {
+    route int
+    goal Goal
+    pieces []PieceScore
+}
+
+// Generates a plan for each job, in job order.  Generators apply and undo
+// subactions on the table as they think, so each of b.workers goroutines gets
+// its own copy of b.table to think on; results don't depend on which worker
+// got which job.
+func (b *RouteBrain) generatePlans(jobs []planJob, c Context) []Plan {
+    plans := make([]Plan, len(jobs))
+    workers := b.workers
+    if workers > len(jobs) {
+        workers = len(jobs)
+    }
+    if b.rollout || workers < 2 {
+        for i, j := range jobs {
+            plans[i] = b.generatePlan(j.route, j.goal, c, j.pieces)
+        }
+        return plans
+    }
+
+    next := int32(-1)
+    var failure interface{}
+    failureLock := sync.Mutex{}
+    wg := sync.WaitGroup{}
+    for w:=0;w<workers;w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            defer func() {
+                if r := recover(); r != nil {
+                    failureLock.Lock()
+                    defer failureLock.Unlock()
+                    if failure == nil {
+                        failure = fmt.Sprintf("%v (in a plan worker)\n%s", r, debug.Stack())
+                    }
+                }
+            }()
+            worker := b.planWorker()
+            for {
+                i := int(atomic.AddInt32(&next, 1))
+                if i >= len(jobs) {
+                    return
+                }
+                plans[i] = worker.generatePlan(jobs[i].route, jobs[i].goal, c, jobs[i].pieces)
+            }
+        }()
+    }
+    wg.Wait()
+
+    // On our goroutine, so Bot.panicking sees it.
+    if failure != nil {
+        panic(failure)
+    }
+    return plans
+}
+
+// A copy of b that only generates plans, on its own copy of the table.
+// Nothing it reads (the table, scores, weights, opponents) changes while
+// plans are generated.
+func (b *RouteBrain) planWorker() *RouteBrain {
+    w := *b
+    w.table = b.table.Clone()
+    return &w
+}
+
+// Best first.  Equal plans keep the order they were generated in (by route,
+// then Goal), so ties always go the same way.
+func sortPlans(plans []Plan) {
+    sort.SliceStable(plans, func(i, j int) bool {
+        return plans[i].FitnessValue > plans[j].FitnessValue
+    })
+}
@@ -0,0 +1,368 @@
+package bot
+
+import (
+    "fmt"
+    "math/rand"
+    "local/hansa/log"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// RandomBrain plays a random legal move at every decision, to fuzz the game
+// (see game/simulation.go).  It doesn't decide what is legal itself: it lists
+// everything that might be (skipping only the obviously impossible), and tries
+// them in random order one at a time, with the game rejecting the illegal
+// ones.  That picks uniformly among the legal ones of a uniformly random kind
+// (place, bag, move, clear...); uniformly over all of them, there are so many
+// ways to shuffle pieces between routes that games never end.
+type RandomBrain struct {
+    identity simple.Identity
+    gameId int
+    rng *rand.Rand
+
+    player int
+    color simple.PlayerColor
+    table simple.Table
+    turnState simple.TurnState
+
+    // What we haven't tried yet at this decision, after the one we sent.
+    untried []message.Client
+}
+
+func (b *RandomBrain) handleStartGame(d message.NotifyStartGameData) {
+    b.table = d.Table
+    b.turnState = simple.NoneTurnState
+    b.findPlayer()
+}
+
+func (b *RandomBrain) handleFullGame(d message.NotifyFullGameData) []message.Client {
+    b.table = d.Table
+    b.turnState = d.TurnState
+    b.findPlayer()
+    return b.decide()
+}
+
+func (b *RandomBrain) handleNotifySubaction(d message.NotifySubactionData) []message.Client {
+    b.table.ApplySubaction(d.Subaction, b.identity)
+
+    // We mirror every subaction as sent, so a mismatch is the game's bug (or
+    // ApplySubaction's), and simulations should report it.
+    if desynced(&b.table, d) {
+        panic(fmt.Sprintf("Table doesn't match the server's after %v", d.Subaction))
+    }
+    b.turnState = d.TurnState
+    return b.decide()
+}
+
+func (b *RandomBrain) handleNotifyNextTurn(d message.NotifyNextTurnData) []message.Client {
+    b.turnState = d.TurnState
+    return b.decide()
+}
+
+func (b *RandomBrain) handleNotifyEndBump(d message.NotifyEndBumpData) []message.Client {
+    b.turnState = d.TurnState
+    return b.decide()
+}
+
+func (b *RandomBrain) handleNotifySubactionError(d message.NotifySubactionErrorData) []message.Client {
+    return b.next()
+}
+
+func (b *RandomBrain) findPlayer() {
+    for i, pb := range b.table.PlayerBoards {
+        if pb.Identity == b.identity {
+            b.player = i
+            b.color = pb.Color
+        }
+    }
+}
+
+// A fresh decision, if it's ours to make.
+func (b *RandomBrain) decide() []message.Client {
+    first, kinds, last := b.candidates()
+    b.rng.Shuffle(len(kinds), func(i, j int) {
+        kinds[i], kinds[j] = kinds[j], kinds[i]
+    })
+    b.untried = []message.Client{}
+    for _, k := range append(append(first, kinds...), last) {
+        b.rng.Shuffle(len(k), func(i, j int) {
+            k[i], k[j] = k[j], k[i]
+        })
+        b.untried = append(b.untried, k...)
+    }
+    if len(b.untried) == 0 {
+        return nil
+    }
+    return b.next()
+}
+
+func (b *RandomBrain) next() []message.Client {
+    if len(b.untried) == 0 {
+        b.debugf("Nothing we tried was legal, forfeiting")
+        return forfeit(&b.table, b.turnState, b.player)
+    }
+    r := b.untried[0]
+    b.untried = b.untried[1:]
+    return []message.Client{r}
+}
+
+// Everything that might be legal now by kind, or nothing if it isn't our
+// move.  EndTurn and EndBump are only offered when the game will take them: it
+// answers those with a notification rather than a NotifySubactionError.
+//
+// Left to chance, the game would never end: we'd end turns early, move
+// pieces off routes as often as onto them, and clear routes into our Stock
+// without an office.  So we always clear a route as soon as we can, into a
+// city if we can (first, in order), and only end a turn with actions left when
+// nothing else works (last).
+func (b *RandomBrain) candidates() (first [][]message.Client, kinds [][]message.Client, last []message.Client) {
+    ts := b.turnState
+    kind := func() {
+        kinds = append(kinds, []message.Client{})
+    }
+    subaction := func(source simple.Location, dest simple.Location, p simple.Piece) []message.Client {
+        if p == (simple.Piece{}) || dest == simple.NoneLocation {
+            return nil
+        }
+        return []message.Client{message.Client{
+            CType: message.DoSubaction,
+            Data: simple.Subaction{
+                Source: source,
+                Dest: dest,
+                Piece: p,
+            },
+        }}
+    }
+    add := func(source simple.Location, dest simple.Location, p simple.Piece) {
+        kinds[len(kinds)-1] = append(kinds[len(kinds)-1], subaction(source, dest, p)...)
+    }
+
+    if ts.Type == simple.Bumping {
+        if ts.BumpingPlayer != b.player {
+            return
+        }
+        valid := b.table.ValidBumps(ts.BumpingLocation)
+        kind()
+        if !ts.BumpingMoved {
+            for _, v := range valid {
+                add(ts.BumpingLocation, v, b.table.GetPiece(ts.BumpingLocation))
+            }
+            return
+        }
+        if ts.BumpingReplaces > 0 {
+            for _, s := range b.sources() {
+                for _, v := range valid {
+                    add(s, v, b.table.GetPiece(s))
+                }
+            }
+        }
+        kinds = append(kinds, []message.Client{message.Client{
+            CType: message.EndBump,
+            Data: message.EndBumpData{},
+        }})
+        return
+    }
+    if ts.Player != b.player {
+        return
+    }
+
+    switch ts.Type {
+        case simple.BumpPaying:
+            kind()
+            for _, s := range b.shapes(6) {
+                add(s, b.open(5), b.table.GetPiece(s))
+            }
+            return
+        case simple.Clearing:
+            kind()
+            route := b.table.Board.Routes[ts.ClearingRouteId]
+            for i, p := range route.Spots {
+                l := simple.Location{
+                    Type: simple.RouteLocationType,
+                    Id: route.Id,
+                    Index: i,
+                }
+                add(l, b.open(5), p)
+                for _, d := range b.cityDests(route) {
+                    add(l, d, p)
+                }
+            }
+            kind()
+            pb := b.table.PlayerBoards[b.player]
+            for track, pieces := range [][]simple.Piece{pb.Keys, pb.Actions, pb.Priviledge, pb.Books, pb.Bags} {
+                for i, p := range pieces {
+                    if p != (simple.Piece{}) {
+                        add(b.location(track, i), b.open(6), p)
+                        break
+                    }
+                }
+            }
+            return
+    }
+
+    last = []message.Client{message.Client{
+        CType: message.EndTurn,
+        Data: message.EndTurnData{},
+    }}
+    // Place (and bump), bags, and move.
+    kind()
+    for _, s := range b.shapes(6) {
+        for _, d := range b.routeSpots(true) {
+            add(s, d, b.table.GetPiece(s))
+        }
+    }
+    kind()
+    for _, s := range b.shapes(5) {
+        add(s, b.open(6), b.table.GetPiece(s))
+    }
+    empty := b.routeSpots(false)
+    first = [][]message.Client{[]message.Client{}, []message.Client{}}
+    kind()
+    for _, route := range b.table.Board.Routes {
+        full := true
+        for _, p := range route.Spots {
+            full = full && p.PlayerColor == b.color
+        }
+        for i, p := range route.Spots {
+            if p.PlayerColor != b.color {
+                continue
+            }
+            l := simple.Location{
+                Type: simple.RouteLocationType,
+                Id: route.Id,
+                Index: i,
+            }
+            for _, d := range empty {
+                add(l, d, p)
+            }
+            if full {
+                for _, d := range b.cityDests(route) {
+                    first[0] = append(first[0], subaction(l, d, p)...)
+                }
+                first[1] = append(first[1], subaction(l, b.open(5), p)...)
+            }
+        }
+    }
+
+    // Out of actions, we can only finish the one we're in.
+    if ts.ActionsLeft == 0 {
+        first = nil
+        switch ts.Type {
+            case simple.Bags:
+                kinds = kinds[1:2]
+            case simple.Moving:
+                kinds = kinds[2:3]
+            default:
+                kinds = nil
+        }
+    }
+    return
+}
+
+// One of each shape in our Stock (5) or Supply (6); which one doesn't matter.
+func (b *RandomBrain) shapes(index int) []simple.Location {
+    r := []simple.Location{}
+    seen := map[simple.Shape]bool{}
+    pieces := b.table.PlayerBoards[b.player].Stock
+    if index == 6 {
+        pieces = b.table.PlayerBoards[b.player].Supply
+    }
+    for i, p := range pieces {
+        if p != (simple.Piece{}) && !seen[p.Shape] {
+            seen[p.Shape] = true
+            r = append(r, b.location(index, i))
+        }
+    }
+    return r
+}
+
+// What we could replace a bump with: Stock and Supply shapes, and our pieces
+// on the board.
+func (b *RandomBrain) sources() []simple.Location {
+    r := append(b.shapes(5), b.shapes(6)...)
+    for _, route := range b.table.Board.Routes {
+        for i, p := range route.Spots {
+            if p.PlayerColor == b.color {
+                r = append(r, simple.Location{
+                    Type: simple.RouteLocationType,
+                    Id: route.Id,
+                    Index: i,
+                })
+            }
+        }
+    }
+    return r
+}
+
+// Empty route spots, and if bump, spots with other players' pieces too.
+func (b *RandomBrain) routeSpots(bump bool) []simple.Location {
+    r := []simple.Location{}
+    for _, route := range b.table.Board.Routes {
+        for i, p := range route.Spots {
+            if p == (simple.Piece{}) || (bump && p.PlayerColor != b.color) {
+                r = append(r, simple.Location{
+                    Type: simple.RouteLocationType,
+                    Id: route.Id,
+                    Index: i,
+                })
+            }
+        }
+    }
+    return r
+}
+
+// The open offices and Coellen spots at either end of route.
+func (b *RandomBrain) cityDests(route simple.Route) []simple.Location {
+    r := []simple.Location{}
+    for _, id := range []int{route.LeftCityId, route.RightCityId} {
+        city := b.table.Board.Cities[id]
+        for i, o := range city.Offices {
+            if o.Piece == (simple.Piece{}) {
+                r = append(r, simple.Location{
+                    Type: simple.CityLocationType,
+                    Id: id,
+                    Index: i,
+                })
+                break
+            }
+        }
+        for i, s := range city.Coellen.Spots {
+            if s.Piece == (simple.Piece{}) {
+                r = append(r, simple.Location{
+                    Type: simple.CityLocationType,
+                    Id: id,
+                    Index: i,
+                    Subindex: 2,
+                })
+            }
+        }
+    }
+    return r
+}
+
+// The first empty slot in our Stock (5) or Supply (6).
+func (b *RandomBrain) open(index int) simple.Location {
+    pieces := b.table.PlayerBoards[b.player].Stock
+    if index == 6 {
+        pieces = b.table.PlayerBoards[b.player].Supply
+    }
+    for i, p := range pieces {
+        if p == (simple.Piece{}) {
+            return b.location(index, i)
+        }
+    }
+    return simple.NoneLocation
+}
+
+func (b *RandomBrain) location(index int, subindex int) simple.Location {
+    return simple.Location{
+        Type: simple.PlayerLocationType,
+        Id: b.player,
+        Index: index,
+        Subindex: subindex,
+    }
+}
+
+func (b *RandomBrain) debugf(msg string, fargs ...interface{}) {
+    log.Debug(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
+}
@@ -0,0 +1,199 @@
+package bot
+
+import (
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// How many times a brain may resync in one turn before it stops trusting its
+// own plans and forfeits the rest of the turn (or bump), so a brain that
+// keeps planning something illegal can't stall the game.
+const maxResyncs = 2
+
+// Brains that keep their own table embed this.  A NotifySubactionError means
+// our table doesn't match the server's (or we can't play on it), so we ask for
+// a NotifyFullGame and Bot drops everything until it comes; it has all of it.
+// The brain's handleFullGame then rebuilds from it and replans.
+type resync struct {
+    pending bool
+    count int
+
+    // Set when the server turned down our EndTurn or EndBump.
+    forfeiting bool
+}
+
+// Bot checks this to know when to drop messages, and tells us when the
+// server rejects how we ended our turn or bump.
+type resyncer interface {
+    resyncing() bool
+    rejected() []message.Client
+}
+
+func (r *resync) resyncing() bool {
+    return r.pending
+}
+
+// Returns nil once we've given up for this turn and even forfeiting failed;
+// there's nothing left to try.
+func (r *resync) request() []message.Client {
+    if r.count > maxResyncs {
+        return nil
+    }
+    r.pending = true
+    r.count++
+    return []message.Client{message.Client{
+        CType: message.RequestFullGame,
+        Data: message.RequestFullGameData{},
+    }}
+}
+
+// The server answers an EndTurn or EndBump it won't take with a client error,
+// not a NotifySubactionError.  Replanning would likely end the same way, so we
+// resync and then forfeit, which finishes whatever the game is waiting on.
+func (r *resync) rejected() []message.Client {
+    r.forfeiting = true
+    return r.request()
+}
+
+// Called with every NotifyFullGame; returns whether it answers our request,
+// and whether we should forfeit instead of trying again (we have resynced too
+// often this turn, or our turn's end was rejected).
+func (r *resync) received() (resynced bool, giveUp bool) {
+    resynced = r.pending
+    giveUp = resynced && (r.count > maxResyncs || r.forfeiting)
+    r.pending = false
+    r.forfeiting = false
+    return resynced, giveUp
+}
+
+// Whether t (with d applied) no longer matches the server's table.  Servers
+// that don't send a Hash never mismatch.
+func desynced(t *simple.Table, d message.NotifySubactionData) bool {
+    return d.Hash != 0 && t.Hash() != d.Hash
+}
+
+// A new turn; start counting again.
+func (r *resync) reset() {
+    r.count = 0
+}
+
+// The least we can do to keep the game moving: put a bumped piece on the
+// first valid spot, pay for a bump, or finish clearing a route (taking any
+// track award, which the game waits for) and end our turn.  Returns nil when
+// we have nothing to do.
+func forfeit(t *simple.Table, ts simple.TurnState, player int) []message.Client {
+    r := []message.Client{}
+    do := func(s simple.Subaction) {
+        r = append(r, message.Client{
+            CType: message.DoSubaction,
+            Data: s,
+        })
+    }
+    pb := t.PlayerBoards[player]
+    open := func(pieces []simple.Piece, index int) []simple.Location {
+        l := []simple.Location{}
+        for i, p := range pieces {
+            if p == (simple.Piece{}) {
+                l = append(l, simple.Location{
+                    Type: simple.PlayerLocationType,
+                    Id: player,
+                    Index: index,
+                    Subindex: i,
+                })
+            }
+        }
+        return l
+    }
+
+    if ts.Type == simple.Bumping {
+        if ts.BumpingPlayer != player {
+            return nil
+        }
+        if !ts.BumpingMoved {
+            valid := t.ValidBumps(ts.BumpingLocation)
+            if len(valid) == 0 {
+                return nil
+            }
+            do(simple.Subaction{
+                Source: ts.BumpingLocation,
+                Dest: valid[0],
+                Piece: t.GetPiece(ts.BumpingLocation),
+            })
+        }
+        return append(r, message.Client{
+            CType: message.EndBump,
+            Data: message.EndBumpData{},
+        })
+    }
+    if ts.Player != player {
+        return nil
+    }
+
+    switch ts.Type {
+        case simple.BumpPaying:
+            // The bumped player moves next, not us.
+            stock := open(pb.Stock, 5)
+            cost := ts.BumpPayingCost
+            for i, p := range pb.Supply {
+                if cost == 0 || len(stock) == 0 {
+                    break
+                }
+                if p == (simple.Piece{}) {
+                    continue
+                }
+                do(simple.Subaction{
+                    Source: simple.Location{
+                        Type: simple.PlayerLocationType,
+                        Id: player,
+                        Index: 6,
+                        Subindex: i,
+                    },
+                    Dest: stock[0],
+                    Piece: p,
+                })
+                stock = stock[1:]
+                cost--
+            }
+            return r
+        case simple.Clearing:
+            // The turn stays Clearing until a track award is taken; declining
+            // the office is enough.
+            if ts.ClearingAward != simple.CoellenAward && pb.CanAward(ts.ClearingAward) {
+                supply := open(pb.Supply, 6)
+                index, subindex := pb.AwardClearLocation(ts.ClearingAward)
+                source := simple.Location{
+                    Type: simple.PlayerLocationType,
+                    Id: player,
+                    Index: index,
+                    Subindex: subindex,
+                }
+                if len(supply) > 0 {
+                    do(simple.Subaction{
+                        Source: source,
+                        Dest: supply[0],
+                        Piece: t.GetPiece(source),
+                    })
+                }
+            }
+            stock := open(pb.Stock, 5)
+            for i, p := range t.Board.Routes[ts.ClearingRouteId].Spots {
+                if p == (simple.Piece{}) || len(stock) == 0 {
+                    continue
+                }
+                do(simple.Subaction{
+                    Source: simple.Location{
+                        Type: simple.RouteLocationType,
+                        Id: ts.ClearingRouteId,
+                        Index: i,
+                    },
+                    Dest: stock[0],
+                    Piece: p,
+                })
+                stock = stock[1:]
+            }
+    }
+    return append(r, message.Client{
+        CType: message.EndTurn,
+        Data: message.EndTurnData{},
+    })
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+    "testing"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// Player 0 has just cleared a full route 0 and may still take award (and the
+// office, if canOffice).
+func clearingTestTable(award simple.Award, canOffice bool) (simple.Table, simple.TurnState) {
+    t := simple.Table{
+        Board: simple.NewBase45Board(),
+        PlayerBoards: simple.NewBasePlayerBoards(),
+        Tokens: []simple.Token{},
+    }
+    c := t.PlayerBoards[0].Color
+    for i, _ := range t.Board.Routes[0].Spots {
+        t.Board.Routes[0].Spots[i] = simple.Piece{PlayerColor: c, Shape: simple.CubeShape}
+    }
+    ts := simple.NoneTurnState
+    ts.Type = simple.Clearing
+    ts.Player = 0
+    ts.ClearingRouteId = 0
+    ts.ClearingAward = award
+    ts.ClearingCanOffice = canOffice
+    return t, ts
+}
+
+func TestForfeitClearing(t *testing.T) {
+    tests := []struct{
+        award simple.Award
+        canOffice bool
+    }{
+        {simple.NoneAward, true},
+        {simple.NoneAward, false},
+        {simple.CoellenAward, true},
+        {simple.KeysAward, true},
+        {simple.ActionsAward, true},
+        {simple.PriviledgeAward, true},
+        {simple.BagsAward, true},
+        {simple.DiscsAward, true},
+    }
+    for _, test := range tests {
+        table, ts := clearingTestTable(test.award, test.canOffice)
+        before := table.PlayerBoards[0].AwardTrackRemaining(test.award)
+        r := forfeit(&table, ts, 0)
+        if len(r) == 0 || r[len(r)-1].CType != message.EndTurn {
+            t.Errorf("award %d: forfeit didn't end the turn: %v", test.award, r)
+            continue
+        }
+
+        // As the game would see it: nothing may be left to clear or award.
+        for _, m := range r[:len(r)-1] {
+            table.ApplySubaction(m.Data.(simple.Subaction), simple.EmptyIdentity)
+        }
+        for i, p := range table.Board.Routes[0].Spots {
+            if p != (simple.Piece{}) {
+                t.Errorf("award %d: route spot %d still has %v", test.award, i, p)
+            }
+        }
+        after := table.PlayerBoards[0].AwardTrackRemaining(test.award)
+        if test.award != simple.NoneAward && test.award != simple.CoellenAward && after != before - 1 {
+            t.Errorf("award %d: track went from %d to %d pieces, the award wasn't taken", test.award, before, after)
+        }
+    }
+}
@@ -1,8 +1,8 @@
 package bot
 
 import (
-    "encoding/json"
     "fmt"
+    "math"
     "sort"
     "strings"
     "local/hansa/log"
@@ -15,12 +15,44 @@ type RouteBrain struct {
     identity simple.Identity
     gameId int
     weights WeightSet
+    handicap *handicap // nil to play our best
+
+    // Set by brains built on this one (MCTSBrain) to pick among our plans on
+    // our turn instead of taking the best; it reorders plans so plans[0] is
+    // played.
+    choose func(plans []Plan, actions int)
+
+    // How many goroutines generate our plans (see planner.go); under 2
+    // generates them on ours.
+    workers int
+
+    // A throwaway brain playing out someone else's search: it doesn't log
+    // and doesn't check itself for table mutation (the search does).
+    rollout bool
+
+    // Explanations of our decisions since Bot last took them, and how many
+    // plans they weighed (for pacing).
+    thoughts []message.BotThoughtsData
+    considered int
+
+    // If set, every plan we choose (outside rollouts) and what we weighed it
+    // against goes here (see Decision).
+    record func(Decision)
+    decisions int
+
+    // Set when we've lost track of the server's table.
+    resync
+
+    // What we expect each opponent could do next turn, as of the plan we're
+    // choosing.
+    opponents opponentModel
 
     // Initialized when we get a startgame message
     player int
     color simple.PlayerColor
     table simple.Table
     scores []int
+    turnState simple.TurnState // the last the server sent
 
     // When we are bumped, we respond and set this to true.  This is necessary
     // because of NotifySubaction's conflation of current state and what it
@@ -35,6 +67,7 @@ type RouteBrain struct {
 
 func (b *RouteBrain) handleStartGame(d message.NotifyStartGameData) {
     b.table = d.Table
+    b.turnState = simple.NoneTurnState
     for i, pb := range d.Table.PlayerBoards {
         if pb.Identity == b.identity {
             b.player = i
@@ -46,6 +79,55 @@ func (b *RouteBrain) handleStartGame(d message.NotifyStartGameData) {
         len(d.Table.PlayerBoards), simple.PlayerColorNames[b.color])
 }
 
+// We get this instead of a startgame message when we take over a seat in a
+// game that is already running, or when we asked for it to resync, so we may
+// need to act right away.
+func (b *RouteBrain) handleFullGame(d message.NotifyFullGameData) []message.Client {
+    b.table = d.Table
+    b.scores = append([]int{}, d.Scores...)
+    b.turnState = d.TurnState
+    b.handledBump = false
+    b.postBumpPlan = Plan{}
+    for i, pb := range d.Table.PlayerBoards {
+        if pb.Identity == b.identity {
+            b.player = i
+            b.color = pb.Color
+        }
+    }
+    resynced, giveUp := b.resync.received()
+    ts := d.TurnState
+    if giveUp {
+        b.errorf("Forfeiting the turn after %d resyncs", b.resync.count)
+        return forfeit(&b.table, ts, b.player)
+    }
+    if resynced {
+        b.infof("Resynced from the server's table")
+    } else {
+        b.debugf("Taking over a running game as %s", simple.PlayerColorNames[b.color])
+    }
+
+    if ts.Type == simple.Bumping && ts.BumpingPlayer == b.player {
+        b.handledBump = true
+        return b.handleBump(message.NotifySubactionData{TurnState: ts})
+    }
+    if ts.Type == simple.NoneTurnStateType && ts.Player == b.player {
+        if ts.ActionsLeft == 0 {
+            return []message.Client{message.Client{
+                CType: message.EndTurn,
+                Data: message.EndTurnData{},
+            }}
+        }
+        return b.chooseAndExecutePlans(ts.ActionsLeft)
+    }
+    if ts.Type != simple.Bumping && ts.Player == b.player {
+        // We were midway through an action; we can't pick a plan up from
+        // there, so finish it as cheaply as we can.
+        b.debugf("Resuming in the middle of an action, forfeiting the turn")
+        return forfeit(&b.table, ts, b.player)
+    }
+    return []message.Client{}
+}
+
 // This convoluted if bool shit is because I conflated actions with what needs
 // to happen next in a single message.
 func (b *RouteBrain) handleNotifySubaction(d message.NotifySubactionData) []message.Client {
@@ -54,6 +136,13 @@ func (b *RouteBrain) handleNotifySubaction(d message.NotifySubactionData) []mess
         b.scores[i] += s
     }
     b.applySubaction(d.Subaction)
+    b.turnState = d.TurnState
+    if desynced(&b.table, d) {
+        b.errorf("My table doesn't match the server's after %v, resyncing", d.Subaction)
+        if resync := b.resync.request(); resync != nil {
+            return resync
+        }
+    }
 
     // There is only one time when a handleNotifySubaction requires a response:
     // I was just bumped.  So as long as we're not in a bumping state, or even
@@ -71,6 +160,8 @@ func (b *RouteBrain) handleNotifySubaction(d message.NotifySubactionData) []mess
 
 func (b *RouteBrain) handleNotifyNextTurn(d message.NotifyNextTurnData) []message.Client {
     r := []message.Client{}
+    b.resync.reset()
+    b.turnState = d.TurnState
     if d.TurnState.Player != b.player {
         return r
     }
@@ -80,6 +171,7 @@ func (b *RouteBrain) handleNotifyNextTurn(d message.NotifyNextTurnData) []messag
 
 func (b *RouteBrain) handleNotifyEndBump(d message.NotifyEndBumpData) []message.Client {
     b.handledBump = false
+    b.turnState = d.TurnState
     if d.TurnState.Player == b.player {
         b.debugf("I have control back after my bump.")
 
@@ -150,20 +242,24 @@ func (b *RouteBrain) choosePlan(actions int) Plan {
     c := b.buildContext(actions)
     plans := []Plan{}
     b.debugf("choosePlan for %d actions with context %+v", actions, c)
+    b.opponents = b.modelOpponents()
 
     // First we generate plans without piece moves.  Generating a plan with a
     // PointsGoal and no pieces to move is guaranteed to succeed (though it may
     // be a very low scoring plan).
-    routePointsPlans := map[int]Plan{}
+    jobs := []planJob{}
     for i, _ := range b.table.Board.Routes {
         for _, g := range allGoals {
-            p := b.generatePlan(i, g, c, []PieceScore{})
-            if p.Goal == PointsGoal {
-                routePointsPlans[i] = p
-            }
-            if p.Goal != NoneGoal {
-                plans = append(plans, p)
-            }
+            jobs = append(jobs, planJob{route: i, goal: g, pieces: []PieceScore{}})
+        }
+    }
+    routePointsPlans := map[int]Plan{}
+    for i, p := range b.generatePlans(jobs, c) {
+        if p.Goal == PointsGoal {
+            routePointsPlans[jobs[i].route] = p
+        }
+        if p.Goal != NoneGoal {
+            plans = append(plans, p)
         }
     }
 
@@ -174,21 +270,24 @@ func (b *RouteBrain) choosePlan(actions int) Plan {
     // Now we are able to generate a version of every plan that prefers to move
     // pieces instead of placing them.
     if len(piecesWorstToBest) > 0 {
-        for i, _ := range b.table.Board.Routes {
-            for _, g := range allGoals {
-                p := b.generatePlan(i, g, c, piecesWorstToBest)
-                if p.Goal != NoneGoal {
-                    plans = append(plans, p)
-                }
+        for i, _ := range jobs {
+            jobs[i].pieces = piecesWorstToBest
+        }
+        for _, p := range b.generatePlans(jobs, c) {
+            if p.Goal != NoneGoal {
+                plans = append(plans, p)
             }
         }
     }
 
+    // Late, points only matter for where they leave us at the end.
+    if c.GameTime == LateGame {
+        b.projectPlans(plans, b.weights[c.GameTime])
+    }
+
     // Let's sort all of the plans by their FitnessValue.  The best plan we
     // have found so far will be in the 0-index.
-    sort.Slice(plans, func(i, j int) bool {
-        return plans[i].FitnessValue > plans[j].FitnessValue
-    })
+    sortPlans(plans)
 
     // Each of these plans may only have used a subset of possible move
     // subactions.  For example, you may need to move 2 pieces while clearing a
@@ -204,15 +303,16 @@ func (b *RouteBrain) choosePlan(actions int) Plan {
     // Now that we have the plans fully fleshed out, let's resort them.  We may
     // have increased the Fitness Score of a plan by adding leftover piece
     // moves in a used action.
-    sort.Slice(plans, func(i, j int) bool {
-        return plans[i].FitnessValue > plans[j].FitnessValue
-    })
+    sortPlans(plans)
 
     // We're done, but let's get some diagnostics before execute.
     allFitness := map[Goal][]float64{}
     for _, p := range plans {
         allFitness[p.Goal] = append(allFitness[p.Goal], p.FitnessValue)
     }
+    if !b.rollout {
+        b.considered += len(plans)
+    }
     str := fmt.Sprintf("Considered %d plans:", len(plans))
     for g, fs := range allFitness {
         max := 0.0
@@ -230,9 +330,28 @@ func (b *RouteBrain) choosePlan(actions int) Plan {
         parts = append(parts, fmt.Sprintf("%d:%.2f", p.Location.Id, p.Score))
     }
     b.debugf("Board pieces valued at {%s}", strings.Join(parts, " "))
+    b.handicap.choose(plans)
+    if b.choose != nil {
+        b.choose(plans, actions)
+    }
+
+    // A plan the table can't take would panic below, or the server would
+    // reject it, so we fall back to the next one.
+    for len(plans) > 1 {
+        err := b.checkPlan(plans[0])
+        if err == "" {
+            break
+        }
+        b.errorf("Dropping a %s plan on route %d: %s", goalNames[plans[0].Goal], plans[0].RouteId, err)
+        plans = plans[1:]
+    }
     b.debugf("Chose a %s plan to %s on route %d with fitness %.2f",
         lengthNames[plans[0].Length], goalNames[plans[0].Goal], plans[0].RouteId, plans[0].FitnessValue)
     b.debugf("Fitness calculation: %s", plans[0].FitnessDescription)
+    b.think(fmt.Sprintf("%s. Board pieces valued at {%s}", str, strings.Join(parts, " ")), plans)
+    if b.record != nil && !b.rollout {
+        b.record(b.decision(plans, actions, c))
+    }
     if plans[0].LeftoverMoves > 0 {
         b.debugf("Plan used moves but didn't need all %d, so unrelated moves were added.",
             b.table.PlayerBoards[b.player].GetBooks())
@@ -278,6 +397,13 @@ func (b *RouteBrain) useLeftoverMoves(plans []Plan, index int, piecesWorstToBest
         }
     }
 
+    // Many plans fill the same spots, so we can't move two pieces to one, or
+    // to one this plan fills itself.
+    used := map[simple.Location]bool{}
+    for _, s := range plans[index].Subactions {
+        used[s.Dest] = true
+    }
+
     leftoverMoves := plans[index].LeftoverMoves
     ss := []simple.Subaction{}
     fitnessAdjustment := 0.0
@@ -294,6 +420,7 @@ func (b *RouteBrain) useLeftoverMoves(plans []Plan, index int, piecesWorstToBest
         for _, s := range plans[i].Subactions {
             if moveTarget != simple.NoneLocation &&
                 !(s.Dest.Type == simple.PlayerLocationType && s.Dest.Index == 5) {
+                used[moveTarget] = true
                 ss = append(ss, simple.Subaction{
                     Source: myMovablePieces[0].Location,
                     Dest: moveTarget,
@@ -314,11 +441,12 @@ func (b *RouteBrain) useLeftoverMoves(plans []Plan, index int, piecesWorstToBest
             // by moving pieces to this route; the current plan wouldn't have
             // leftoverMoves if this was possible, and the current plan's
             // subactions aren't applied now so it may look possible.
-            if s.Dest.Type == simple.RouteLocationType && s.Dest.Id != routeId {
+            if s.Dest.Type == simple.RouteLocationType && s.Dest.Id != routeId && !used[s.Dest] {
                 moveTarget = s.Dest
             }
         }
         if moveTarget != simple.NoneLocation {
+            used[moveTarget] = true
             ss = append(ss, simple.Subaction{
                 Source: myMovablePieces[0].Location,
                 Dest: moveTarget,
@@ -419,24 +547,33 @@ func (b *RouteBrain) buildContext(actions int) Context {
 // This can not mutate b.table nor c permanently.  It may mutate b.table while
 // thinking about a plan, but should always undo everything.
 func (b *RouteBrain) generatePlan(r int, g Goal, c Context, p []PieceScore) Plan {
-    var ret Plan
-    before := b.serializeTable()
+    if b.rollout {
+        return b.generatePlanUnchecked(r, g, c, p)
+    }
+    before := b.table.Clone()
+    ret := b.generatePlanUnchecked(r, g, c, p)
+    if !before.Equals(&b.table) {
+        panic(fmt.Sprintf("RouteBrain mutated table! r=%d g=%d c=%v p=%v sa=%v before=%s, after=%s",
+            r, g, c, p, ret.Subactions, before.Json(), b.table.Json()))
+    }
+    return ret
+}
+
+// Checking for mutation costs far more than generating the plan, so searches
+// that generate thousands of plans use this and check once around the whole
+// search.
+func (b *RouteBrain) generatePlanUnchecked(r int, g Goal, c Context, p []PieceScore) Plan {
     switch g {
         case AwardGoal:
-            ret = b.generateAwardPlan(r, c, p)
+            return b.generateAwardPlan(r, c, p)
         case OfficeGoal:
-            ret = b.generateOfficePlan(r, c, p)
+            return b.generateOfficePlan(r, c, p)
         case PointsGoal:
-            ret = b.generatePointsPlan(r, c, simple.NoneShape, p)
+            return b.generatePointsPlan(r, c, simple.NoneShape, p)
         case BlockGoal:
-            ret = b.generateBlockPlan(r, c, p)
-    }
-    after := b.serializeTable()
-    if before != after {
-        panic(fmt.Sprintf("RouteBrain mutated table! r=%d g=%d c=%v p=%v sa=%v before=%s, after=%s",
-            r, g, c, p, ret.Subactions, before, after))
+            return b.generateBlockPlan(r, c, p)
     }
-    return ret
+    return Plan{}
 }
 
 func (b *RouteBrain) generateAwardPlan(r int, c Context, ps []PieceScore) Plan {
@@ -478,46 +615,20 @@ func (b *RouteBrain) generateAwardPlan(r int, c Context, ps []PieceScore) Plan {
     p.FitnessValue = fitness.Value(b.weights[c.GameTime])
     p.FitnessDescription = fitness.Calculation(b.weights[c.GameTime])
 
-    // If we cleared, we also need to take our reward.
-    if p.Length == ShortPlan || p.Length == FullPlan {
-
-        // The coellen reward is very similar to taking a disc office.  We
-        // look for a clear subaction with a disc (guaranteed because we
-        // passed disc=true to generatePointsPlan) and replace the
-        // destination with the coellen table.
-        if a == simple.CoellenAward {
-            for i2:=len(p.Subactions)-1;i2>=0;i2-- {
-                candidate := p.Subactions[i2]
-                if candidate.Dest.Type == simple.PlayerLocationType &&
-                    candidate.Piece.Shape == simple.DiscShape {
-                    p.Subactions[i2].Dest = b.getCoellenTarget()
-                    break
-                }
-            }
-
-        // All other rewards require us to move a new piece from a
-        // playerboard area to the supply.  In order to know which
-        // subindexes in our supply are open, we need to apply all previous
-        // subactions.
-        } else {
-            b.applySubactions(p.Subactions)
-            index, subindex := b.table.PlayerBoards[b.player].AwardClearLocation(a)
-            piece := b.myCube()
-            if a == simple.DiscsAward {
-                piece = b.myDisc()
-            }
-            s := simple.Subaction{
-                Source: simple.Location{
-                    Type: simple.PlayerLocationType,
-                    Id: b.player,
-                    Index: index,
-                    Subindex: subindex,
-                },
-                Dest: b.myOpenSupply(),
-                Piece: piece,
+    // If we cleared, we also need to take our reward.  clearRouteForPoints
+    // already took any track award, since the game won't let a clear end
+    // without it.  The coellen reward is very similar to taking a disc
+    // office.  We look for a clear subaction with a disc (guaranteed because
+    // we passed disc=true to generatePointsPlan) and replace the destination
+    // with the coellen table.
+    if (p.Length == ShortPlan || p.Length == FullPlan) && a == simple.CoellenAward {
+        for i2:=len(p.Subactions)-1;i2>=0;i2-- {
+            candidate := p.Subactions[i2]
+            if candidate.Dest.Type == simple.PlayerLocationType &&
+                candidate.Piece.Shape == simple.DiscShape {
+                p.Subactions[i2].Dest = b.getCoellenTarget()
+                break
             }
-            b.table.UndoSubactions(p.Subactions)
-            p.Subactions = append(p.Subactions, s)
         }
     }
     return p
@@ -576,7 +687,7 @@ func (b *RouteBrain) scoreBoardPieces(routePointsPlans map[int]Plan) []PieceScor
             panic(fmt.Sprintf("Bot failed to generate PointsPlan for route '%d'", ps.Location.Id))
         }
     }
-    sort.Slice(r, func(i, j int) bool {
+    sort.SliceStable(r, func(i, j int) bool {
         return r[i].Score < r[j].Score
     })
     return r
@@ -721,6 +832,16 @@ func (b *RouteBrain) generatePointsPlan(
     // in the route.   This may be if we find it already there, move it there,
     // or place it there.
     stockAndSupply := min(10, b.myStockAndSupplyCount())
+
+    // Only the first bump of each player denies them anything.
+    bumped := map[simple.PlayerColor]bool{}
+    denied := func(color simple.PlayerColor) float64 {
+        if bumped[color] {
+            return 0
+        }
+        bumped[color] = true
+        return b.opponents.denial(r, b.playerOf(color))
+    }
     discToBump := []simple.Location{}
     cubeToBump := []simple.Location{}
     openSpot := []simple.Location{}
@@ -751,6 +872,7 @@ func (b *RouteBrain) generatePointsPlan(
                 Disc: p.Shape == simple.DiscShape,
                 Bags: b.table.PlayerBoards[b.player].GetBags(),
                 StockAndSupply: stockAndSupply,
+                Denied: denied(p.PlayerColor),
             })
         }
     }
@@ -1060,9 +1182,32 @@ func (b *RouteBrain) executePlansUntilBump(plans []Plan) []message.Client {
     return r
 }
 
-// Assumes we are complete on the route, and have an action.
+// Assumes we are complete on the route, and have an action.  Mutates.  Also
+// takes the route's track award if we can, which the game
+// waits for before it lets us end the turn.
 func (b *RouteBrain) clearRouteForPoints(r int) []simple.Subaction {
     ss := []simple.Subaction{}
+    a := b.getRouteAward(r)
+    pb := b.table.PlayerBoards[b.player]
+    if a != simple.NoneAward && a != simple.CoellenAward && pb.CanAward(a) {
+        index, subindex := pb.AwardClearLocation(a)
+        piece := b.myCube()
+        if a == simple.DiscsAward {
+            piece = b.myDisc()
+        }
+        s := simple.Subaction{
+            Source: simple.Location{
+                Type: simple.PlayerLocationType,
+                Id: b.player,
+                Index: index,
+                Subindex: subindex,
+            },
+            Dest: b.myOpenSupply(),
+            Piece: piece,
+        }
+        b.applySubaction(s)
+        ss = append(ss, s)
+    }
     for i, spot := range b.table.Board.Routes[r].Spots {
         s := simple.Subaction{
             Source: simple.Location{
@@ -1093,6 +1238,7 @@ func (b *RouteBrain) generateBlockPlan(r int, c Context, movablePieces []PieceSc
     // derivative will score highly, and we will use that plan instead.
     someoneElse := simple.NonePlayerColor
     openSpots := []simple.Location{}
+    blocked := map[simple.PlayerColor]bool{}
     for i, s := range b.table.Board.Routes[r].Spots {
         if s.PlayerColor == simple.NonePlayerColor {
             openSpots = append(openSpots, simple.Location{
@@ -1107,6 +1253,10 @@ func (b *RouteBrain) generateBlockPlan(r int, c Context, movablePieces []PieceSc
             if someoneElse != simple.NonePlayerColor && someoneElse != s.PlayerColor {
                 fitness.DoublePlayer = true
             }
+            if !blocked[s.PlayerColor] {
+                blocked[s.PlayerColor] = true
+                fitness.Denied += b.opponents.denial(r, b.playerOf(s.PlayerColor))
+            }
             someoneElse = s.PlayerColor
         }
     }
@@ -1405,8 +1555,15 @@ func (b *RouteBrain) myOpenSupply() simple.Location {
     return simple.NoneLocation
 }
 
-func (b *RouteBrain) handleNotifySubactionError(d message.NotifySubactionErrorData) {
+// Either our table is wrong or we planned something illegal on it; either
+// way, start again from the server's.
+func (b *RouteBrain) handleNotifySubactionError(d message.NotifySubactionErrorData) []message.Client {
     b.errorf("I submitted a bad Subaction: '%v' table: %s", d, b.table.JsonPretty())
+    r := b.resync.request()
+    if r == nil {
+        b.errorf("Unable to forfeit either, waiting for the next turn")
+    }
+    return r
 }
 
 // Note this should not permanently mutate b.table; we only permanently mutate
@@ -1414,7 +1571,6 @@ func (b *RouteBrain) handleNotifySubactionError(d message.NotifySubactionErrorDa
 // way to keep our representation valid.
 func (b *RouteBrain) handleBump(d message.NotifySubactionData) []message.Client {
     b.debugf("I have been bumped at %v and can replace %d pieces", d.TurnState.BumpingLocation, d.TurnState.BumpingReplaces)
-    c := b.buildContext(1)
 
     r := []message.Client{}
     toUndo := []simple.Subaction{}
@@ -1428,13 +1584,10 @@ func (b *RouteBrain) handleBump(d message.NotifySubactionData) []message.Client
         })
     }
 
-    // Figure out which pieces I'm moving.  I give them a very low score to be
-    // sure plans are incentivized by moving these pieces over "placing" new
-    // pieces.
+    // Figure out which pieces I'm moving.
     ps := []PieceScore{PieceScore{
         Piece: b.table.GetPiece(d.TurnState.BumpingLocation),
         Location: d.TurnState.BumpingLocation,
-        Score: -100.0,
     }}
     for i:=0;i<d.TurnState.BumpingReplaces;i++ {
         l := b.myStock(b.myCube())
@@ -1451,7 +1604,6 @@ func (b *RouteBrain) handleBump(d message.NotifySubactionData) []message.Client
             ps = append(ps, PieceScore{
                 Piece: b.table.GetPiece(l),
                 Location: l,
-                Score: -100.0,
             })
         }
     }
@@ -1464,48 +1616,29 @@ func (b *RouteBrain) handleBump(d message.NotifySubactionData) []message.Client
     }
     b.debugf("I can replace from Stock+Supply")
 
-    // For each piece, find the routes I can legally place on, and chose the
-    // one with the highest score.  Make sure we are applying our replacement
-    // so that ValidBumps can catch if we can go 1 distance further from the
-    // bumpinglocation on the next iteration.
+    // Place each piece where it leaves us the best next turn, applying our
+    // choices as we go so that ValidBumps can catch if we can go 1 distance
+    // further from the bumpinglocation on the next iteration.  taken keeps
+    // two pieces from choosing the same spot either way.
+    //
+    // This is greedy: the bumped piece picks first and each replacement picks
+    // given the ones before it, so we can miss a pair of spots that is only
+    // good together.  Judging every combination (up to 3 pieces over every
+    // valid spot) would cost a round of plan generation each, and we're
+    // holding up the bumper's turn.
+    taken := map[simple.Location]bool{}
     for _, p := range ps {
-        fitness := map[int]Plan{}
-        for _, l := range b.table.ValidBumps(d.TurnState.BumpingLocation) {
-            if _, ok := fitness[l.Id]; ok {
-                continue
-            }
-            for _, g := range allGoals {
-                p := b.generatePlan(l.Id, g, c, []PieceScore{p})
-                if old, ok := fitness[l.Id]; !ok || old.FitnessValue < p.FitnessValue {
-                    fitness[l.Id] = p
-                }
-            }
-        }
-        plans := []Plan{}
-        for i, p := range fitness {
-            b.debugf("Generated the following bump response plan: %d(%.2f):%v", i, p.FitnessValue, p.Subactions)
-            plans = append(plans, p)
+        dest := b.chooseBumpDest(p, d.TurnState.BumpingLocation, taken)
+        if dest == simple.NoneLocation {
+            b.errorf("Nowhere to put %v", p.Piece)
+            break
         }
-        sort.Slice(plans, func (i, j int) bool {
-            return plans[i].FitnessValue > plans[j].FitnessValue
+        taken[dest] = true
+        do(simple.Subaction{
+            Source: p.Location,
+            Dest: dest,
+            Piece: p.Piece,
         })
-        for _, plan := range plans {
-            handled := false
-            for _, s := range plan.Subactions {
-                if s.Source == p.Location {
-                    do(simple.Subaction{
-                        Source: p.Location,
-                        Dest: s.Dest,
-                        Piece: p.Piece,
-                    })
-                    handled = true
-                    break
-                }
-            }
-            if handled {
-                break
-            }
-        }
     }
 
     b.table.UndoSubactions(toUndo)
@@ -1520,11 +1653,21 @@ func (b *RouteBrain) handleBumpWithBoardMoves(d simple.TurnState, ps []PieceScor
 
     // TODO: Complicated and unusual.  Let's do something valid (move the
     // bumped piece) but not use any other moves.
+    // With nothing taken, there's only no dest if every route we can reach
+    // is full.
+    dest := b.chooseBumpDest(ps[0], d.BumpingLocation, map[simple.Location]bool{})
+    if dest == simple.NoneLocation {
+        b.errorf("Nowhere to put %v", ps[0].Piece)
+        return []message.Client{message.Client{
+            CType: message.EndBump,
+            Data: message.EndBumpData{},
+        }}
+    }
     return []message.Client{message.Client{
         CType: message.DoSubaction,
         Data: simple.Subaction{
             Source: d.BumpingLocation,
-            Dest: b.table.ValidBumps(d.BumpingLocation)[0],
+            Dest: dest,
             Piece: ps[0].Piece,
         },
     }, message.Client{
@@ -1533,6 +1676,105 @@ func (b *RouteBrain) handleBumpWithBoardMoves(d simple.TurnState, ps []PieceScor
     }}
 }
 
+// A bump destination is judged by the turn it leaves us: with the piece
+// there, the best plan we could make next turn (on every route and goal, so
+// including finishing a route).  Ties go to the destination whose own route
+// has the better plan.
+type bumpCandidate struct {
+    dest simple.Location
+    next Plan
+    local float64
+}
+
+// Never chooses a spot in taken; returns NoneLocation if every valid spot is
+// taken.  Leaves b.table as it was.
+func (b *RouteBrain) chooseBumpDest(p PieceScore, bumpingLocation simple.Location, taken map[simple.Location]bool) simple.Location {
+    actions := b.table.PlayerBoards[b.player].GetActions()
+    candidates := []bumpCandidate{}
+    before := b.table.Clone()
+    valid := []simple.Location{}
+    for _, l := range b.table.ValidBumps(bumpingLocation) {
+        if !taken[l] {
+            valid = append(valid, l)
+        }
+    }
+    for _, l := range valid {
+        s := simple.Subaction{
+            Source: p.Location,
+            Dest: l,
+            Piece: p.Piece,
+        }
+        b.applySubaction(s)
+        c := b.buildContext(actions)
+        candidate := bumpCandidate{dest: l, local: math.Inf(-1)}
+        for i, _ := range b.table.Board.Routes {
+            for _, g := range allGoals {
+                plan := b.generatePlanUnchecked(i, g, c, []PieceScore{})
+                if plan.Goal == NoneGoal {
+                    continue
+                }
+                if candidate.next.Goal == NoneGoal || plan.FitnessValue > candidate.next.FitnessValue {
+                    candidate.next = plan
+                }
+                if i == l.Id && plan.FitnessValue > candidate.local {
+                    candidate.local = plan.FitnessValue
+                }
+            }
+        }
+        b.table.UndoSubactions([]simple.Subaction{s})
+
+        b.debugf("Bump to %v: next turn %s %s on route %d (%.2f), here %.2f",
+            l, lengthNames[candidate.next.Length], goalNames[candidate.next.Goal],
+            candidate.next.RouteId, candidate.next.FitnessValue, candidate.local)
+        candidates = append(candidates, candidate)
+    }
+    if !before.Equals(&b.table) {
+        panic(fmt.Sprintf("RouteBrain mutated table choosing a bump for %v! before=%s, after=%s",
+            p, before.Json(), b.table.Json()))
+    }
+    if len(candidates) == 0 {
+        return simple.NoneLocation
+    }
+
+    sort.SliceStable(candidates, func(i, j int) bool {
+        if candidates[i].next.FitnessValue != candidates[j].next.FitnessValue {
+            return candidates[i].next.FitnessValue > candidates[j].next.FitnessValue
+        }
+        return candidates[i].local > candidates[j].local
+    })
+    values := []float64{}
+    for _, c := range candidates {
+        values = append(values, c.next.FitnessValue)
+    }
+    order := b.handicap.rank(values)
+    chosen := candidates[order[0]]
+    if b.record != nil && !b.rollout {
+        b.record(b.bumpDecision(candidates, order, b.buildContext(actions)))
+    }
+    if !b.rollout {
+        thought := message.BotThoughtsData{
+            Summary: fmt.Sprintf("Bumped %v: considered %d spots by my next turn", p.Piece, len(candidates)),
+        }
+        for _, i := range order {
+            if len(thought.Plans) == thoughtPlans {
+                break
+            }
+            c := candidates[i]
+            thought.Plans = append(thought.Plans, message.PlanThought{
+                Goal: fmt.Sprintf("Bump to %d/%d then %s", c.dest.Id, c.dest.Index, goalNames[c.next.Goal]),
+                RouteId: c.next.RouteId,
+                Fitness: c.next.FitnessValue,
+                Calculation: c.next.FitnessDescription,
+            })
+        }
+        b.thoughts = append(b.thoughts, thought)
+    }
+    b.debugf("Bumping %v to %v because next turn I can %s on route %d (%.2f): %s",
+        p.Piece, chosen.dest, goalNames[chosen.next.Goal], chosen.next.RouteId,
+        chosen.next.FitnessValue, chosen.next.FitnessDescription)
+    return chosen.dest
+}
+
 func (b *RouteBrain) myStockAndSupplyCount() int {
     return b.myStockCount() + b.mySupplyCount()
 }
@@ -1613,6 +1855,32 @@ func (b *RouteBrain) mySupplyTarget() simple.Location {
     return simple.NoneLocation
 }
 
+// Why the subactions of p we play now (up to its first bump) can't be played
+// on our table, or "" if they can.  Besides what the table checks, a move
+// can't bump.  Doesn't mutate.
+func (b *RouteBrain) checkPlan(p Plan) string {
+    applied := []simple.Subaction{}
+    defer func() {
+        b.table.UndoSubactions(applied)
+    }()
+    for i, s := range p.Subactions {
+        err := b.table.ValidateSubaction(s)
+        if err == "" && s.Source.Type == simple.RouteLocationType &&
+            s.Dest.Type == simple.RouteLocationType && b.table.GetPiece(s.Dest) != (simple.Piece{}) {
+            err = "a move can't bump"
+        }
+        if err != "" {
+            return fmt.Sprintf("subaction %d %v: %s", i, s, err)
+        }
+        b.applySubaction(s)
+        applied = append(applied, s)
+        if len(p.Bumps) > 0 && p.Bumps[0] == i {
+            break
+        }
+    }
+    return ""
+}
+
 func (b *RouteBrain) applySubactions(ss []simple.Subaction) {
     for _, s := range ss {
         b.applySubaction(s)
@@ -1627,13 +1895,60 @@ func (b *RouteBrain) applySubaction(s simple.Subaction) {
         s.Piece == (simple.Piece{}) {
         panic(fmt.Sprintf("Bot attempted to apply invalid Subaction: %+v", s))
     }
+    if b.rollout {
+        if err := b.table.ValidateSubaction(s); err != "" {
+            panic(rolloutFailure(fmt.Sprintf("%+v: %s", s, err)))
+        }
+    }
     b.table.ApplySubaction(s, b.identity)
 }
 
+// How many plans we explain per decision.
+const thoughtPlans = 5
+
+// Records why we chose plans[0] for anyone watching.
+func (b *RouteBrain) think(summary string, plans []Plan) {
+    if b.rollout {
+        return
+    }
+    d := message.BotThoughtsData{Summary: summary}
+    for i, p := range plans {
+        if i == thoughtPlans {
+            break
+        }
+        d.Plans = append(d.Plans, message.PlanThought{
+            Goal: fmt.Sprintf("%s %s", lengthNames[p.Length], goalNames[p.Goal]),
+            RouteId: p.RouteId,
+            Fitness: p.FitnessValue,
+            Calculation: p.FitnessDescription,
+        })
+    }
+    b.thoughts = append(b.thoughts, d)
+}
+
+func (b *RouteBrain) takeThoughts() []message.BotThoughtsData {
+    r := b.thoughts
+    b.thoughts = nil
+    return r
+}
+
+func (b *RouteBrain) takeConsidered() int {
+    r := b.considered
+    b.considered = 0
+    return r
+}
+
 func (b *RouteBrain) debugf(msg string, fargs ...interface{}) {
+    if b.rollout {
+        return
+    }
     log.Debug(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
 }
 
+func (b *RouteBrain) infof(msg string, fargs ...interface{}) {
+    log.Info(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
+}
+
 func (b *RouteBrain) errorf(msg string, fargs ...interface{}) {
     log.Error(fmt.Sprintf("(G%d) (Bot%s) (P%d) %s", b.gameId, b.identity, b.player, msg), fargs...)
 }
@@ -1674,14 +1989,6 @@ func minFloat(x, y float64) float64 {
     return y
 }
 
-func (b *RouteBrain) serializeTable() string {
-    bytes, err := json.Marshal(b.table)
-    if err != nil {
-        panic(fmt.Sprintf("RouteBrain: serializeTable() error marshalling: '%s' b.table: %+v", err, b.table))
-    }
-    return string(bytes)
-}
-
 func min (x, y int) int {
     if x < y {
         return x
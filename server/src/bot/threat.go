@@ -0,0 +1,222 @@
+package bot
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "local/hansa/simple"
+)
+
+// The game ends once someone reaches endScore or endFilledCities cities are
+// full (see gameEndIfNecessary).
+const (
+    endScore = 20
+    endFilledCities = 10
+)
+
+// A route an opponent could clear on their next turn, and what it would get
+// them.  This is deliberately rough: they place one piece per action and
+// bump for one action each, need a Bags action if their Supply runs out, and
+// never move.
+type Threat struct {
+    Player int
+    RouteId int
+    Actions int
+    Points int // from controlling the route's cities, and the office itself
+    Office bool
+    Award simple.Award
+    BonusRoute bool // the office would complete their bonus route
+    GameEnd bool // and the game with it
+
+    // In about points, already scaled up by how close the game is to ending.
+    Value float64
+}
+
+func (t Threat) String() string {
+    parts := []string{fmt.Sprintf("P%d route %d in %d actions:", t.Player, t.RouteId, t.Actions)}
+    if t.Points > 0 {
+        parts = append(parts, fmt.Sprintf("%d points", t.Points))
+    }
+    if t.Office {
+        parts = append(parts, "office")
+    }
+    if t.Award != simple.NoneAward {
+        parts = append(parts, "award")
+    }
+    if t.BonusRoute {
+        parts = append(parts, "bonus route")
+    }
+    if t.GameEnd {
+        parts = append(parts, "game end")
+    }
+    return fmt.Sprintf("%s (%.2f)", strings.Join(parts, " "), t.Value)
+}
+
+// What every opponent might do next turn, rebuilt before each plan we choose.
+type opponentModel struct {
+    // By RouteId, then by player.
+    routes map[int]map[int]Threat
+
+    // Each opponent's most valuable threat, most valuable first.
+    best []Threat
+}
+
+// How much keeping player from clearing route r is worth to us (0 if they
+// can't next turn anyway).
+func (m opponentModel) denial(r int, player int) float64 {
+    return m.routes[r][player].Value
+}
+
+func (b *RouteBrain) modelOpponents() opponentModel {
+    m := opponentModel{routes: map[int]map[int]Threat{}}
+    t := &b.table
+
+    maxScore := 0
+    for _, s := range b.scores {
+        maxScore = max(maxScore, s)
+    }
+    filled := t.Board.GetFilledCityCount()
+    nearEnd := minFloat(1, maxFloat(float64(maxScore)/endScore, float64(filled)/endFilledCities))
+
+    for p, _ := range t.PlayerBoards {
+        if p == b.player {
+            continue
+        }
+        best := Threat{}
+        for _, route := range t.Board.Routes {
+            threat, ok := b.threat(p, route, nearEnd, filled)
+            if !ok {
+                continue
+            }
+            if m.routes[route.Id] == nil {
+                m.routes[route.Id] = map[int]Threat{}
+            }
+            m.routes[route.Id][p] = threat
+            if threat.Value > best.Value {
+                best = threat
+            }
+        }
+        if best.Value > 0 {
+            m.best = append(m.best, best)
+        }
+    }
+    sort.Slice(m.best, func(i, j int) bool {
+        return m.best[i].Value > m.best[j].Value
+    })
+    for _, threat := range m.best {
+        b.debugf("Threat: %s", threat)
+    }
+    return m
+}
+
+func (b *RouteBrain) threat(p int, route simple.Route, nearEnd float64, filled int) (Threat, bool) {
+    t := &b.table
+    pb := t.PlayerBoards[p]
+    r := Threat{Player: p, RouteId: route.Id, Award: simple.NoneAward}
+
+    // Filling the route, then clearing it.
+    mine := 0
+    disc := false
+    for _, s := range route.Spots {
+        if s.PlayerColor == pb.Color {
+            mine++
+            disc = disc || s.Shape == simple.DiscShape
+        } else {
+            r.Actions++
+        }
+    }
+    if mine == 0 {
+        return r, false
+    }
+    supply := 0
+    for _, s := range pb.Supply {
+        if s != (simple.Piece{}) {
+            supply++
+            disc = disc || s.Shape == simple.DiscShape
+        }
+    }
+    if r.Actions > supply {
+        r.Actions++
+    }
+    r.Actions++
+    if r.Actions > pb.GetActions() {
+        return r, false
+    }
+
+    value := 0.0
+    for _, id := range []int{route.LeftCityId, route.RightCityId} {
+        city := t.Board.Cities[id]
+        if city.GetControl() == pb.Color {
+            r.Points++
+        }
+        if city.Award != simple.NoneAward && pb.CanAward(city.Award) {
+            r.Award = city.Award
+        }
+    }
+    if r.Award != simple.NoneAward {
+        value += 1
+    }
+
+    // The best office they could take at either end.
+    officePoints := 0
+    fills := false
+    for _, id := range []int{route.LeftCityId, route.RightCityId} {
+        city := t.Board.Cities[id]
+        for i, o := range city.Offices {
+            if o.Piece != (simple.Piece{}) {
+                continue
+            }
+            if pb.GetPriviledge() < o.Priviledge || (o.Shape == simple.DiscShape && !disc) {
+                break
+            }
+            bonus := !t.Board.GetBonusRouteCompleted(pb.Color) && b.completesBonusRoute(pb.Color, id)
+            if !r.Office || bonus {
+                r.Office = true
+                r.BonusRoute = bonus
+                officePoints = o.Points
+                fills = i == len(city.Offices)-1
+            }
+            break
+        }
+    }
+    r.Points += officePoints
+    r.GameEnd = fills && filled+1 >= endFilledCities
+    if r.Office {
+        value += 1
+    }
+    if r.BonusRoute {
+        value += 2
+    }
+    if p < len(b.scores) && b.scores[p] + r.Points >= endScore {
+        r.GameEnd = true
+    }
+    if r.GameEnd {
+        value += 3
+    }
+
+    value += float64(r.Points)
+    r.Value = value * (1 + nearEnd)
+    return r, value > 0
+}
+
+// Whether an office for color in city would complete color's bonus route.
+func (b *RouteBrain) completesBonusRoute(color simple.PlayerColor, city int) bool {
+    board := b.table.Board
+    board.Cities = append([]simple.City{}, board.Cities...)
+    c := board.Cities[city]
+    c.VirtualOffices = append(append([]simple.Piece{}, c.VirtualOffices...), simple.Piece{
+        PlayerColor: color,
+        Shape: simple.CubeShape,
+    })
+    board.Cities[city] = c
+    return board.GetBonusRouteCompleted(color)
+}
+
+func (b *RouteBrain) playerOf(color simple.PlayerColor) int {
+    for i, pb := range b.table.PlayerBoards {
+        if pb.Color == color {
+            return i
+        }
+    }
+    return -1
+}
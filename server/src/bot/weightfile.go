@@ -0,0 +1,265 @@
+package bot
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "local/hansa/simple"
+)
+
+// Weight sets live in JSON files (server/weights/*.json) so they can be tuned
+// and reloaded without a deploy.  A file is one WeightSet and the bots that
+// play it:
+//
+//     {
+//         "Name": "generic",
+//         "Version": 3,
+//         "Bots": ["B1", "B2"],
+//         "Weights": {"Early": {...}, "Mid": {...}, "Late": {...}}
+//     }
+//
+// Each Weights is the Weights struct (see weights.go for what everything
+// means), with Length keyed by plan length name ("Short") and Awards by
+// award name ("Actions").  Bump into the file's Version whenever you change
+// it; games log which Name@Version each bot played.
+type WeightFile struct {
+    Name string
+    Version int
+    Bots []string
+    Weights WeightSet
+}
+
+// What bots play when no file names them.
+var builtinWeights = WeightFile{
+    Name: "builtin",
+    Weights: GenericWeightSet,
+}
+
+func (f WeightFile) String() string {
+    return fmt.Sprintf("%s@%d", f.Name, f.Version)
+}
+
+// Loads every *.json in dir, by the bot ids they name.  Nothing is returned
+// unless every file is valid and no bot is named twice.
+func LoadWeightFiles(dir string) (map[string]WeightFile, error) {
+    paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+    if err != nil {
+        return nil, err
+    }
+    if len(paths) == 0 {
+        return nil, fmt.Errorf("no weight files in '%s'", dir)
+    }
+    sort.Strings(paths)
+
+    r := map[string]WeightFile{}
+    for _, path := range paths {
+        f, err := LoadWeightFile(path)
+        if err != nil {
+            return nil, err
+        }
+        for _, id := range f.Bots {
+            if other, ok := r[id]; ok {
+                return nil, fmt.Errorf("%s: bot %s already plays %s", path, id, other)
+            }
+            r[id] = f
+        }
+    }
+    return r, nil
+}
+
+func LoadWeightFile(path string) (WeightFile, error) {
+    f := WeightFile{}
+    bytes, err := ioutil.ReadFile(path)
+    if err != nil {
+        return f, err
+    }
+    if err = json.Unmarshal(bytes, &f); err != nil {
+        return f, fmt.Errorf("%s: %s", path, err)
+    }
+    if f.Name == "" || f.Version < 1 {
+        return f, fmt.Errorf("%s: needs a Name and a Version of at least 1", path)
+    }
+    if err = f.Weights.Validate(); err != nil {
+        return f, fmt.Errorf("%s (%s): %s", path, f, err)
+    }
+    return f, nil
+}
+
+// The award track lengths (and Coellen spots) that Weights.Awards must cover,
+// indexed by what's left on the track.
+var awardLevels = map[simple.Award]int{
+    simple.DiscsAward: 4,
+    simple.PriviledgeAward: 4,
+    simple.BagsAward: 4,
+    simple.CoellenAward: 4,
+    simple.ActionsAward: 6,
+    simple.KeysAward: 5,
+}
+
+// Checks that every lookup the fitness calculations make will find a value,
+// and returns all the problems at once.
+func (w WeightSet) Validate() error {
+    problems := []string{}
+    for _, t := range []GameTime{EarlyGame, MidGame, LateGame} {
+        weights, ok := w[t]
+        if !ok {
+            problems = append(problems, fmt.Sprintf("missing %s", gameTimeNames[t]))
+            continue
+        }
+        for _, p := range weights.problems() {
+            problems = append(problems, fmt.Sprintf("%s: %s", gameTimeNames[t], p))
+        }
+        if t == LateGame && weights.Place <= 0 {
+            problems = append(problems, fmt.Sprintf("%s: Place must be above 0", gameTimeNames[t]))
+        }
+    }
+    if len(problems) > 0 {
+        return errors.New(strings.Join(problems, "; "))
+    }
+    return nil
+}
+
+func (w Weights) problems() []string {
+    r := []string{}
+    for l := ShortPlan; l <= UncompletablePlan; l++ {
+        if _, ok := w.Length[l]; !ok {
+            r = append(r, fmt.Sprintf("Length has no %s", lengthNames[l]))
+        }
+    }
+    if _, ok := w.Move[200]; !ok {
+        r = append(r, "Move has no 200")
+    }
+    for k, _ := range w.Move {
+        if k < -200 || k > 200 {
+            r = append(r, fmt.Sprintf("Move key %d is outside -200 to 200", k))
+        }
+    }
+    r = append(r, coversLevels("Bump", w.Bump, []int{3, 5, 7, 100})...)
+    r = append(r, coversLevels("Block", w.Block, []int{2, 3, 4, 5})...)
+    for a, n := range awardLevels {
+        if len(w.Awards[a]) < n {
+            r = append(r, fmt.Sprintf("Awards[%s] needs %d values", awardNames[a], n))
+        }
+    }
+    for i:=0;i<=6;i++ {
+        if _, ok := w.Network[i]; !ok {
+            r = append(r, "Network needs every value from 0 to 6")
+            break
+        }
+    }
+    sort.Strings(r)
+    return r
+}
+
+// Bump and Block are indexed by a level, then by Stock+Supply (capped at 10).
+func coversLevels(name string, m map[int]map[int]float64, levels []int) []string {
+    r := []string{}
+    for _, l := range levels {
+        for i:=0;i<=10;i++ {
+            if _, ok := m[l][i]; !ok {
+                r = append(r, fmt.Sprintf("%s[%d] needs every value from 0 to 10", name, l))
+                break
+            }
+        }
+    }
+    return r
+}
+
+var gameTimeNames = map[GameTime]string{
+    EarlyGame: "Early",
+    MidGame: "Mid",
+    LateGame: "Late",
+}
+
+var awardNames = map[simple.Award]string{
+    simple.DiscsAward: "Discs",
+    simple.PriviledgeAward: "Priviledge",
+    simple.BagsAward: "Bags",
+    simple.CoellenAward: "Coellen",
+    simple.ActionsAward: "Actions",
+    simple.KeysAward: "Keys",
+}
+
+// Map keys in files are names, not numbers.
+
+func (t GameTime) MarshalText() ([]byte, error) {
+    return marshalName(int(t), gameTimeNames[t])
+}
+
+func (t *GameTime) UnmarshalText(b []byte) error {
+    for k, v := range gameTimeNames {
+        if v == string(b) {
+            *t = k
+            return nil
+        }
+    }
+    return fmt.Errorf("unknown GameTime '%s'", b)
+}
+
+func (l PlanLength) MarshalText() ([]byte, error) {
+    return marshalName(int(l), lengthNames[l])
+}
+
+func (l *PlanLength) UnmarshalText(b []byte) error {
+    for k, v := range lengthNames {
+        if v == string(b) {
+            *l = k
+            return nil
+        }
+    }
+    return fmt.Errorf("unknown PlanLength '%s'", b)
+}
+
+func marshalName(i int, name string) ([]byte, error) {
+    if name == "" {
+        return nil, errors.New("no name for " + strconv.Itoa(i))
+    }
+    return []byte(name), nil
+}
+
+// simple.Award is a number everywhere else, so Weights renames its Awards
+// itself.
+type weightsJson Weights
+
+func (w Weights) MarshalJSON() ([]byte, error) {
+    awards := map[string][]float64{}
+    for a, v := range w.Awards {
+        if awardNames[a] == "" {
+            return nil, fmt.Errorf("no name for award %d", a)
+        }
+        awards[awardNames[a]] = v
+    }
+    return json.Marshal(struct {
+        weightsJson
+        Awards map[string][]float64
+    }{weightsJson(w), awards})
+}
+
+func (w *Weights) UnmarshalJSON(b []byte) error {
+    v := struct {
+        *weightsJson
+        Awards map[string][]float64
+    }{weightsJson: (*weightsJson)(w)}
+    if err := json.Unmarshal(b, &v); err != nil {
+        return err
+    }
+    w.Awards = map[simple.Award][]float64{}
+    for name, values := range v.Awards {
+        found := false
+        for a, n := range awardNames {
+            if n == name {
+                w.Awards[a] = values
+                found = true
+            }
+        }
+        if !found {
+            return fmt.Errorf("unknown award '%s'", name)
+        }
+    }
+    return nil
+}
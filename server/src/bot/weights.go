@@ -64,6 +64,13 @@ type Weights struct {
     // How bad is it to give others points when clearing a route.
     OthersPoints float64
 
+    // In the LateGame, plans are judged by how they change our projected
+    // final standing (see evaluator.go) instead of the points above: each
+    // point of lead over the best opponent gained is MyPoints, each lost is
+    // OthersPoints, and each place gained multiplies by Place (each lost
+    // divides).  Only read in the LateGame.
+    Place float64
+
     //
     // Award Goals
     //
@@ -130,8 +137,20 @@ type Weights struct {
     // How much less attractive is it to block when there are 2 other players
     // already holding some of the route?
     DoublePlayerBlock float64
+
+    //
+    // Blocks and Bumps
+    //
+
+    // How much we like getting in the way of what an opponent could do next
+    // turn (see threat.go).  Each Block or Bump is multiplied by 1 + Denial *
+    // the value of the threat it stops, in about points (already higher the
+    // closer the game is to ending).  0 ignores opponents.
+    Denial float64
 }
 
+// Built in, for bots no weight file names (see weightfile.go).  The weight
+// files start as copies of this.
 var GenericWeightSet = WeightSet{
     EarlyGame: GenericEarlyWeights,
     MidGame: GenericMidWeights,
@@ -194,6 +213,7 @@ var GenericEarlyWeights = Weights{
     },
     DoublePieceBlock: 0.5,
     DoublePlayerBlock: 0.6,
+    Denial: 0.05,
 }
 var GenericMidWeights = Weights{
     Length: map[PlanLength]float64 {
@@ -251,6 +271,7 @@ var GenericMidWeights = Weights{
     },
     DoublePieceBlock: 0.5,
     DoublePlayerBlock: 0.6,
+    Denial: 0.1,
 }
 var GenericLateWeights = Weights{
     Length: map[PlanLength]float64 {
@@ -278,6 +299,7 @@ var GenericLateWeights = Weights{
     DiscBump: 0.6,
     MyPoints: 1.7,
     OthersPoints: 0.5,
+    Place: 1.5,
     Awards: map[simple.Award][]float64 {
         simple.DiscsAward: []float64{0.0, 1.5, 1.3, 1.5},
         simple.PriviledgeAward: []float64{0.0, 1.5, 1.3, 1.5},
@@ -308,10 +330,5 @@ var GenericLateWeights = Weights{
     },
     DoublePieceBlock: 0.5,
     DoublePlayerBlock: 0.6,
+    Denial: 0.2,
 }
-
-var coryWeights = GenericWeightSet
-var jacobWeights = GenericWeightSet
-var caniceWeights = GenericWeightSet
-var bcripeWeights = GenericWeightSet
-var derekWeights = GenericWeightSet
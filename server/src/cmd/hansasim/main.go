@@ -0,0 +1,128 @@
+// Plays games between random bots against the real game engine, looking for
+// panics and stalls.  Every failure is dumped (seed and moves) to -out; rerun
+// one with -seed <seed> -games 1, or play its moves again exactly (even once
+// the bots have changed) with -replay <dump>.
+//
+//     go run ./cmd/hansasim -games 500 -players 4
+//     go run ./cmd/hansasim -replay sim-1234.json
+//
+// With -dataset, RouteBrains play each other instead and every plan they
+// chose (and every spot they chose for a bumped piece) in a completed game is
+// appended to the dataset as a line of JSON (bot.Decision), with what its
+// features are in <dataset>.features.json.
+//
+//     go run ./cmd/hansasim -games 200 -dataset selfplay.jsonl
+//
+// With -model, every bot is a ModelBrain playing that model (see bot.Model)
+// instead, and -dataset is optional.
+//
+//     go run ./cmd/hansasim -games 20 -model linear-1.json
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "time"
+    "local/hansa/bot"
+    "local/hansa/game"
+    "local/hansa/log"
+)
+
+func main() {
+    games := flag.Int("games", 100, "how many games to play")
+    seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the first game; each next game adds 1")
+    players := flag.Int("players", 0, "players per game, 4-5, or 0 to alternate")
+    out := flag.String("out", ".", "where to dump failed games")
+    maxMessages := flag.Int("max-messages", 50000, "give up on a game after this many bot messages")
+    stall := flag.Duration("stall", 10 * time.Second, "give up on a game after this long without a bot message")
+    dataset := flag.String("dataset", "", "play RouteBrains and write their decisions here")
+    replay := flag.String("replay", "", "play the moves of this dumped game again instead")
+    model := flag.String("model", "", "play ModelBrains with this model")
+    flag.Parse()
+
+    log.Init("/tmp", log.InfoLevel)
+
+    if *replay != "" {
+        r, err := game.LoadSimulation(*replay)
+        if err != nil {
+            fmt.Printf("Couldn't load %s: %s\n", *replay, err)
+            os.Exit(1)
+        }
+        rr := game.Replay(r)
+        fmt.Printf("seed %d (%dp): was %s after %d moves, replayed %s after %d moves: %s\n",
+            r.Seed, r.Players, r.Status, len(r.Moves), rr.Status, len(rr.Moves), rr.Panic)
+        for _, v := range rr.Violations {
+            fmt.Printf("    %s\n", v)
+        }
+        if rr.Stack != "" {
+            fmt.Println(rr.Stack)
+        }
+        if rr.Failed() {
+            os.Exit(1)
+        }
+        return
+    }
+
+    var decisions *os.File
+    features := map[string]bot.FeatureNames{}
+    if *dataset != "" {
+        f, err := os.OpenFile(*dataset, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+        if err != nil {
+            fmt.Printf("Couldn't open dataset: %s\n", err)
+            os.Exit(1)
+        }
+        defer f.Close()
+        decisions = f
+    }
+
+    failures := 0
+    for i:=0;i<*games;i++ {
+        p := *players
+        if p == 0 {
+            p = 4 + i % 2
+        }
+        r := game.Simulate(game.SimulationConfig{
+            Seed: *seed + int64(i),
+            Players: p,
+            MaxMessages: *maxMessages,
+            StallTimeout: *stall,
+            SelfPlay: decisions != nil || *model != "",
+            Model: *model,
+        })
+        if decisions != nil {
+            if err := bot.WriteDecisions(decisions, r.Decisions); err != nil {
+                fmt.Printf("Couldn't write dataset: %s\n", err)
+                os.Exit(1)
+            }
+        }
+        if r.Features != nil {
+            features[r.Features.Board] = *r.Features
+        }
+        if r.Status == game.SimulationInvalid {
+            fmt.Printf("seed %d: %s\n", r.Seed, r.Panic)
+            os.Exit(1)
+        }
+        if !r.Failed() {
+            fmt.Printf("seed %d (%dp): %s in %d moves, scores %v\n", r.Seed, p, r.Status, len(r.Moves), r.Scores)
+            continue
+        }
+        failures++
+        path, err := r.Dump(*out)
+        if err != nil {
+            fmt.Printf("seed %d (%dp): %s, couldn't dump: %s\n", r.Seed, p, r.Status, err)
+            continue
+        }
+        fmt.Printf("seed %d (%dp): %s after %d moves: %s (%s)\n", r.Seed, p, r.Status, len(r.Moves), r.Panic, path)
+    }
+    fmt.Printf("%d of %d games failed\n", failures, *games)
+    if decisions != nil {
+        if err := bot.WriteFeatureNames(*dataset + ".features.json", features); err != nil {
+            fmt.Printf("Couldn't write features: %s\n", err)
+            os.Exit(1)
+        }
+    }
+    if failures > 0 {
+        os.Exit(1)
+    }
+}
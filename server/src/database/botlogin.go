@@ -0,0 +1,63 @@
+package database
+
+import (
+    "errors"
+    "golang.org/x/crypto/bcrypt"
+    "local/hansa/log"
+    "local/hansa/simple"
+)
+
+// External bots are accounts for bots running in their own process, which
+// connect over the websocket API with an API key instead of a cookie.
+//
+// create table botlogin (
+//     id varchar primary key, -- X prefixed
+//     name varchar not null,
+//     owner varchar not null, -- P id of whoever runs it
+//     key bytea not null -- bcrypt hash of the API key
+// );
+type BotLogin struct {
+    Id string `db:"id"`
+    Name string `db:"name"`
+    Owner string `db:"owner"`
+    Key []byte `db:"key"`
+}
+
+func (db *DB) getBotLogin(id string) (BotLogin, bool) {
+    rows, dberr := db.c.Queryx("select * from botlogin where id=$1", id)
+    if dberr != nil {
+        db.errorf("Unable to select from db: %s", dberr)
+        return BotLogin{}, false
+    }
+
+    var b BotLogin
+    for rows.Next() {
+        dberr = rows.StructScan(&b)
+        if dberr != nil {
+            db.errorf("Error Scanning into BotLogin: %s", dberr)
+            return BotLogin{}, false
+        }
+    }
+    return b, b.Id == id
+}
+
+func (db *DB) GetExternalBot(id string) (simple.Identity, bool) {
+    b, ok := db.getBotLogin(id)
+    if !ok {
+        return simple.EmptyIdentity, false
+    }
+    return simple.NewExternalBotIdentity(b.Id, b.Name), true
+}
+
+func (db *DB) BotSignin(id string, key []byte) (simple.Identity, error) {
+    b, ok := db.getBotLogin(id)
+    if !ok {
+        log.Debug("Bot signin attempt for %s: unknown id", id)
+        return simple.EmptyIdentity, errors.New("id")
+    }
+    if notok := bcrypt.CompareHashAndPassword(b.Key, key); notok != nil {
+        log.Debug("Bot signin attempt for %s: bad key", id)
+        return simple.EmptyIdentity, errors.New("key")
+    }
+    return simple.NewExternalBotIdentity(b.Id, b.Name), nil
+}
@@ -112,6 +112,8 @@ func (db *DB) GetIdentity(id string) (simple.Identity, bool) {
     } else if id[0] == 'B' {
         v, ok := botidentities[id]
         return v, ok
+    } else if id[0] == 'X' {
+        return db.GetExternalBot(id)
     } else if id[0] == 'P' {
         rows, dberr := db.c.Queryx("select * from login where id=$1", id)
         if dberr != nil {
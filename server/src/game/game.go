@@ -4,7 +4,7 @@ import (
     "fmt"
     "math/rand"
     "reflect"
-    "sort"
+    "strings"
     "sync"
     "time"
     "local/hansa/bot"
@@ -26,11 +26,13 @@ type Game struct {
     db *database.DB
     uh *user.Handler
     bm *bot.Manager
+    lobby Lobby
 
     // The way that other components talk to us
     joins chan client.Client
     broadcast chan message.Broadcast
     scoring chan message.Server
+    rematchId chan int
 
     // The way users talk to us.  During Creating, players is uninitialized and
     // all communication is through observers.  Once we transition, players is
@@ -38,6 +40,14 @@ type Game struct {
     observers map[simple.Identity]*client.MultiWebClient
     players []*Player // turn order
     disconnects map[int]bool
+    frozen map[int]bool // resigned without a bot taking over
+    abandonVotes map[int]bool
+    locked map[int]bool // seats humans can't sit in
+    ready map[int]bool
+    readyDeadline time.Time
+    difficulty map[simple.Identity]simple.Difficulty // seated bots
+    watched bool // by any humans, as far as our bots know
+    thoughtWatchers map[simple.Identity]bool
 
     // Lifecycle
     status Status
@@ -46,8 +56,11 @@ type Game struct {
     timeouts chan TimeoutType
     summaryMux sync.Mutex
     summary message.GameSummary
+    rematch *rematchVote
 
     // Game state
+    options simple.GameOptions
+    lastRanking []simple.Identity // best to worst, if we are a rematch
     table *simple.Table
     turnState simple.TurnState
     scores []int
@@ -59,6 +72,20 @@ type Game struct {
     turns []simple.Turn
     actions []simple.Action // only for uncompleted current turn, may be undone.
     subactions []simple.Subaction // only for uncompleted current turn, may be undone.
+
+    // Start tokens and turn order come from here, so a simulation can replay
+    // a game from its seed.
+    rng *rand.Rand
+
+    // Set by simulations (see simulation.go): scoring happens immediately
+    // and every player message is passed to record first.
+    sim bool
+    record func(p int, m message.Client)
+
+    // Debug builds and simulations check the table after every subaction;
+    // broken is what was wrong the first time it failed.
+    checkTable bool
+    broken []string
 }
 
 type GameTimes struct {
@@ -68,29 +95,41 @@ type GameTimes struct {
     complete time.Time
 }
 
-func New(id int, creator simple.Identity, db *database.DB, uh *user.Handler, bm *bot.Manager) *Game {
+func New(id int, creator simple.Identity, options simple.GameOptions, db *database.DB, uh *user.Handler, bm *bot.Manager, l Lobby) *Game {
     return &Game{
         Id: id,
         Creator: creator,
         db: db,
         uh: uh,
         bm: bm,
+        lobby: l,
         joins: make(chan client.Client, 2),
         broadcast: make(chan message.Broadcast, 10),
         scoring: make(chan message.Server, 10),
+        rematchId: make(chan int, 1),
         observers: map[simple.Identity]*client.MultiWebClient{},
         disconnects: map[int]bool{},
+        frozen: map[int]bool{},
+        abandonVotes: map[int]bool{},
+        locked: map[int]bool{},
+        ready: map[int]bool{},
+        difficulty: map[simple.Identity]simple.Difficulty{},
+        thoughtWatchers: map[simple.Identity]bool{},
+        watched: true,
         status: Creating,
         newStatus: Creating,
         times: GameTimes{create: time.Now(), elapsed: []time.Duration{0, 0, 0, 0, 0}},
-        timeouts: make(chan TimeoutType),
+        timeouts: make(chan TimeoutType, 10),
         summaryMux: sync.Mutex{},
+        options: options,
         table: &simple.Table{
             Board: simple.NewBase45Board(),
             PlayerBoards: simple.NewBasePlayerBoards(),
             Tokens: simple.NewBaseTokens(),
         },
         scores: []int{0, 0, 0, 0, 0},
+        rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+        checkTable: simple.DebugBuild,
     }
 }
 
@@ -104,12 +143,45 @@ func (g *Game) Run(initDone chan struct{}) {
     g.updateSummary()
     initDone <- struct{}{}
 
+    g.checkStatus()
+    g.updateSummary()
     for ;g.handleMsg(); {
         g.checkStatus()
         g.updateSummary()
+        g.updateWatched()
     }
     g.checkStatus()
     g.updateSummary()
+    for _, p := range g.players {
+        if b, ok := p.Client.(*bot.Bot); ok {
+            b.Done()
+        }
+    }
+}
+
+// Bots speed up while no humans are connected to watch them.
+func (g *Game) updateWatched() {
+    watched := false
+    for i, p := range g.players {
+        if isHuman(p.Client.Identity()) && !g.disconnects[i] {
+            watched = true
+        }
+    }
+    for i, _ := range g.observers {
+        if isHuman(i) {
+            watched = true
+        }
+    }
+    if watched == g.watched {
+        return
+    }
+    g.debugf("Watched by humans: %t", watched)
+    g.watched = watched
+    for _, p := range g.players {
+        if b, ok := p.Client.(*bot.Bot); ok {
+            b.SetWatched(watched)
+        }
+    }
 }
 
 func (g *Game) Register(c client.Client) {
@@ -128,9 +200,19 @@ func (g *Game) updateSummary() {
 
     is := []simple.Identity{}
     cs := []simple.PlayerColor{}
-    for _, pb := range g.table.PlayerBoards {
+    ws := []string{}
+    for i, pb := range g.table.PlayerBoards {
         is = append(is, pb.Identity)
         cs = append(cs, pb.Color)
+
+        // Players only have clients once the game starts.
+        w := ""
+        if i < len(g.players) {
+            if b, ok := g.players[i].Client.(*bot.Bot); ok {
+                w = b.Weights()
+            }
+        }
+        ws = append(ws, w)
     }
 
     g.summary = message.GameSummary{
@@ -143,6 +225,7 @@ func (g *Game) updateSummary() {
         Colors: cs,
         Scores: g.scores,
         Observers: len(g.observers),
+        BotWeights: ws,
     }
 }
 
@@ -162,6 +245,7 @@ func (g *Game) handleMsg() bool {
         rcase(reflect.ValueOf(g.timeouts)),
         rcase(reflect.ValueOf(g.broadcast)),
         rcase(reflect.ValueOf(g.scoring)),
+        rcase(reflect.ValueOf(g.rematchId)),
     }
     for i, p := range g.players {
         c := p.Client
@@ -198,19 +282,27 @@ func (g *Game) handleMsg() bool {
             panic("g.scoring should never be closed!")
         }
         g.handleScoring(value.Interface().(message.Server))
-    } else if len(g.players) > chosen-4 {
+    } else if chosen == 4 {
+        if !ok {
+            panic("g.rematchId should never be closed!")
+        }
+        g.handleRematchId(value.Interface().(int))
+    } else if len(g.players) > chosen-5 {
         if !ok {
-            p := g.players[chosen-4]
-            g.debugf("Player %s (%s) disconnected", p.Client.Identity())
-            g.disconnects[chosen-4] = true
+            p := g.players[chosen-5]
+            g.debugf("Player %s disconnected", p.Client.Identity())
+            g.disconnects[chosen-5] = true
         } else {
-            g.handlePlayerMsg(chosen-4, g.players[chosen-4], value.Interface().(message.Client))
+            if g.record != nil {
+                g.record(chosen-5, value.Interface().(message.Client))
+            }
+            g.handlePlayerMsg(chosen-5, g.players[chosen-5], value.Interface().(message.Client))
         }
     } else {
         if !ok {
-            delete(g.observers, obOrder[(chosen-4)-len(g.players)])
+            delete(g.observers, obOrder[(chosen-5)-len(g.players)])
         } else {
-            g.handleObserverMsg(g.observers[obOrder[(chosen-4)-len(g.players)]],
+            g.handleObserverMsg(g.observers[obOrder[(chosen-5)-len(g.players)]],
                 value.Interface().(message.Client))
         }
     }
@@ -238,19 +330,7 @@ func (g *Game) handleJoin(c *client.WebClient) {
     g.debugf("HandleJoin %s", c.Identity())
 
     // This won't include the history, but the user can ask for it separately.
-    c.Send(message.Server{
-        SType: message.NotifyFullGame,
-        Time: time.Now(),
-        Data: message.NotifyFullGameData{
-            Status: int(g.status),
-            Creator: g.Creator,
-            Table: *g.table,
-            TurnState: g.turnState,
-            Elapsed: g.castElapsed(),
-            Scores: g.scores,
-            FinalScores: g.finalscores,
-        },
-    })
+    c.Send(g.fullGame())
 
     // Look for this identity as a player or an observer.
     if mc, ok := g.observers[c.Identity()]; ok {
@@ -274,8 +354,41 @@ func (g *Game) handleJoin(c *client.WebClient) {
     g.observers[c.Identity()] = mc
 }
 
+func (g *Game) fullGame() message.Server {
+    return message.Server{
+        SType: message.NotifyFullGame,
+        Time: time.Now(),
+        Data: message.NotifyFullGameData{
+            Status: int(g.status),
+            Creator: g.Creator,
+            Options: g.options,
+            Table: *g.table,
+            TurnState: g.turnState,
+            Elapsed: g.castElapsed(),
+            Scores: g.scores,
+            FinalScores: g.finalscores,
+        },
+    }
+}
+
+// Clients (bots, mostly) that think they've lost track of the game ask for
+// everything again.
+func (g *Game) handleRequestFullGame(c client.Client, d message.RequestFullGameData) {
+    g.debugf("(%s) Requested full game", c.Identity())
+    c.Send(g.fullGame())
+}
+
 func (g *Game) handleTimeout(tt TimeoutType) {
-    // TODO: this
+    switch tt {
+        case RematchTimeoutType:
+            if g.rematch != nil && !time.Now().Before(g.rematch.deadline) {
+                g.checkRematch(true)
+            }
+        case ReadyCheckTimeoutType:
+            g.handleReadyCheckTimeout()
+        default:
+            // TODO: this
+    }
 }
 
 func (g *Game) handleBroadcast(b message.Broadcast) {
@@ -302,6 +415,14 @@ func (g *Game) handleScoring(m message.Server) {
 }
 
 func (g *Game) handlePlayerMsg(i int, p *Player, m message.Client) {
+    if g.frozen[i] {
+        switch m.CType {
+            case message.DoSubaction, message.EndTurn, message.EndBump, message.Resign, message.AbandonVote:
+                g.clientError(p.Client, "Client Error", "You resigned from this game")
+                return
+        }
+    }
+
     switch ty := m.CType; ty {
         case message.RequestSignup:
             g.uh.Handle(p.Client, m)
@@ -315,6 +436,16 @@ func (g *Game) handlePlayerMsg(i int, p *Player, m message.Client) {
             g.handleRequestSitdown(p.Client, m.Data.(message.RequestSitdownData))
         case message.RequestSitdownBot:
             g.handleRequestSitdownBot(p.Client, m.Data.(message.RequestSitdownBotData))
+        case message.RequestColor:
+            g.handleRequestColor(p.Client, m.Data.(message.RequestColorData))
+        case message.UpdateGameOptions:
+            g.handleUpdateGameOptions(p.Client, m.Data.(message.UpdateGameOptionsData))
+        case message.KickSeat:
+            g.handleKickSeat(p.Client, m.Data.(message.KickSeatData))
+        case message.LockSeat:
+            g.handleLockSeat(p.Client, m.Data.(message.LockSeatData))
+        case message.Ready:
+            g.handleReady(p.Client, m.Data.(message.ReadyData))
         case message.StartGame:
             g.handleStartGame(p.Client, m.Data.(message.StartGameData))
         case message.DoSubaction:
@@ -323,10 +454,25 @@ func (g *Game) handlePlayerMsg(i int, p *Player, m message.Client) {
             g.handleEndTurn(i, p.Client, m.Data.(message.EndTurnData))
         case message.EndBump:
             g.handleEndBump(i, p.Client, m.Data.(message.EndBumpData))
+        case message.RequestRematch:
+            g.handleRequestRematch(i, p.Client, m.Data.(message.RequestRematchData))
+        case message.AnswerRematch:
+            g.handleAnswerRematch(i, p.Client, m.Data.(message.AnswerRematchData))
+        case message.Resign:
+            g.handleResign(i, p.Client, m.Data.(message.ResignData))
+        case message.AbandonVote:
+            g.handleAbandonVote(i, p.Client, m.Data.(message.AbandonVoteData))
+        case message.BotThoughts:
+            g.handleBotThoughts(i, p.Client, m.Data.(message.BotThoughtsData))
+        case message.RequestBotThoughts:
+            g.handleRequestBotThoughts(p.Client, m.Data.(message.RequestBotThoughtsData))
+        case message.RequestFullGame:
+            g.handleRequestFullGame(p.Client, m.Data.(message.RequestFullGameData))
         default:
             g.clientError(p.Client, "Client Error", "CType '%s' unhandled by Game (player)",
                 message.CTypeNames[m.CType])
     }
+    g.playFrozen()
 }
 
 func (g *Game) handleObserverMsg(o *client.MultiWebClient, m message.Client) {
@@ -343,8 +489,22 @@ func (g *Game) handleObserverMsg(o *client.MultiWebClient, m message.Client) {
             g.handleRequestSitdown(o, m.Data.(message.RequestSitdownData))
         case message.RequestSitdownBot:
             g.handleRequestSitdownBot(o, m.Data.(message.RequestSitdownBotData))
+        case message.RequestColor:
+            g.handleRequestColor(o, m.Data.(message.RequestColorData))
+        case message.UpdateGameOptions:
+            g.handleUpdateGameOptions(o, m.Data.(message.UpdateGameOptionsData))
+        case message.KickSeat:
+            g.handleKickSeat(o, m.Data.(message.KickSeatData))
+        case message.LockSeat:
+            g.handleLockSeat(o, m.Data.(message.LockSeatData))
+        case message.Ready:
+            g.handleReady(o, m.Data.(message.ReadyData))
         case message.StartGame:
             g.handleStartGame(o, m.Data.(message.StartGameData))
+        case message.RequestBotThoughts:
+            g.handleRequestBotThoughts(o, m.Data.(message.RequestBotThoughtsData))
+        case message.RequestFullGame:
+            g.handleRequestFullGame(o, m.Data.(message.RequestFullGameData))
         default:
             g.clientError(o, "Client Error", "CType '%s' unhandled by Game (observer)",
                 message.CTypeNames[m.CType])
@@ -383,6 +543,10 @@ func (g *Game) handleRequestSitdown(c client.Client, d message.RequestSitdownDat
             g.clientError(c, "Sitdown Error", "%s is already there", i.Name)
             return
         }
+        if g.locked[d.Index] {
+            g.clientError(c, "Sitdown Error", "That seat is locked")
+            return
+        }
         g.debugf("(%s) Sat down", c.Identity())
         g.table.PlayerBoards[d.Index].Identity = c.Identity()
         g.notify(message.Server{
@@ -414,8 +578,13 @@ func (g *Game) handleRequestSitdown(c client.Client, d message.RequestSitdownDat
     })
 }
 
+// External bots (X ids) are seated here too, but play like humans do: they
+// connect to the game themselves.
 func (g *Game) handleRequestSitdownBot(c client.Client, d message.RequestSitdownBotData) {
     identity := g.bm.GetIdentity(d.Id)
+    if identity == simple.EmptyIdentity && strings.HasPrefix(d.Id, "X") && g.db != nil {
+        identity, _ = g.db.GetExternalBot(d.Id)
+    }
     if identity == simple.EmptyIdentity {
         g.clientError(c, "Sitdown Error", "Not a valid Bot ID: '%s'", d.Id)
         return
@@ -455,8 +624,13 @@ func (g *Game) handleRequestSitdownBot(c client.Client, d message.RequestSitdown
             g.clientError(c, "Sitdown Error", "%s is already there", i.Name)
             return
         }
-        g.debugf("(%s) Sat down", identity)
+        if _, ok := simple.DifficultyNames[d.Difficulty]; !ok {
+            g.clientError(c, "Sitdown Error", "Not a valid difficulty: %d", d.Difficulty)
+            return
+        }
+        g.debugf("(%s) Sat down (%s)", identity, simple.DifficultyNames[d.Difficulty])
         g.table.PlayerBoards[d.Index].Identity = identity
+        g.difficulty[identity] = d.Difficulty
         g.notify(message.Server{
             SType: message.NotifySitdown,
             Time: time.Now(),
@@ -464,6 +638,7 @@ func (g *Game) handleRequestSitdownBot(c client.Client, d message.RequestSitdown
                 Identity: identity,
                 Index: d.Index,
                 Sitdown: true,
+                Difficulty: d.Difficulty,
             },
         })
         return
@@ -475,6 +650,7 @@ func (g *Game) handleRequestSitdownBot(c client.Client, d message.RequestSitdown
     }
     g.debugf("(%s) Stood up", identity)
     g.table.PlayerBoards[d.Index].Identity = simple.EmptyIdentity
+    delete(g.difficulty, identity)
     g.notify(message.Server{
         SType: message.NotifySitdown,
         Time: time.Now(),
@@ -496,19 +672,25 @@ func (g *Game) handleStartGame(c client.Client, d message.StartGameData) {
         return
     }
 
-    players := 0
-    for _, pb := range g.table.PlayerBoards {
-        if pb.Identity != simple.EmptyIdentity {
-            players++
-        }
-    }
-    if players < 4 {
+    if g.seated() < 4 {
         g.clientError(c, "StartGame Error", "Only 4-5 players is supported :(")
         return
     }
 
-    g.debugf("Starting Game")
-    g.newStatus = Running
+    // The creator is ready by asking; if they are the only human we can skip
+    // the ready check.
+    for i, pb := range g.table.PlayerBoards {
+        if pb.Identity == g.Creator {
+            g.ready[i] = true
+        }
+    }
+    if len(g.unready()) == 0 {
+        g.debugf("Starting Game")
+        g.newStatus = Running
+        return
+    }
+    g.debugf("Starting ready check")
+    g.newStatus = ReadyCheck
 }
 
 // Validate that the Locations are valid, the piece is present at source, the
@@ -540,6 +722,11 @@ func (g *Game) validateSubaction(p int, c client.Client, d simple.Subaction) boo
         g.subactionError(c, "Dest Error", err)
         return false
     }
+    err = g.table.ValidateSubaction(d)
+    if err != "" {
+        g.subactionError(c, "Subaction Error", err)
+        return false
+    }
 
     return true
 }
@@ -1693,8 +1880,27 @@ func (g *Game) notifySubactionWithScores(s simple.Subaction, ss []int) {
             Scores: ss,
             TurnState: g.turnState,
             Gameend: g.gameend,
+            Hash: g.table.Hash(),
         },
     })
+    g.checkInvariants(s)
+}
+
+// Reports a table that breaks its invariants where it broke, instead of
+// letting some later subaction panic on it.  Only once; after that the table
+// can't be trusted anyway.
+func (g *Game) checkInvariants(s simple.Subaction) {
+    if !g.checkTable || g.broken != nil {
+        return
+    }
+    v := g.table.CheckInvariantsWith(g.turnState)
+    if len(v) == 0 {
+        return
+    }
+    g.broken = v
+    g.errorf("Table invariants broken after %v: %s table: %s", s, strings.Join(v, "; "), g.table.JsonPretty())
+    g.notify(message.NewNotifyNotification(message.NotificationInternalError, "Internal Error",
+        fmt.Sprintf("The table is broken after %v: %s", s, strings.Join(v, "; "))))
 }
 
 func (g *Game) checkStatus() {
@@ -1702,11 +1908,37 @@ func (g *Game) checkStatus() {
         return
     }
 
-    if g.status == Creating && g.newStatus == Running {
+    if g.status == Creating && g.newStatus == ReadyCheck {
+        g.readyDeadline = time.Now().Add(readyCheckTimeout)
+        g.notify(message.Server{
+            SType: message.NotifyReadyCheck,
+            Time: time.Now(),
+            Data: message.NotifyReadyCheckData{
+                Begin: true,
+                Deadline: g.readyDeadline,
+            },
+        })
+        time.AfterFunc(readyCheckTimeout, func() {
+            g.timeouts <- ReadyCheckTimeoutType
+        })
+    }
+
+    if g.status == ReadyCheck && g.newStatus == Creating {
+        g.ready = map[int]bool{}
+        g.notify(message.Server{
+            SType: message.NotifyReadyCheck,
+            Time: time.Now(),
+            Data: message.NotifyReadyCheckData{
+                Begin: false,
+            },
+        })
+    }
+
+    if (g.status == Creating || g.status == ReadyCheck) && g.newStatus == Running {
 
         // Place start tokens
         st := simple.NewBaseStartTokens()
-        rand.Shuffle(len(st), func(i, j int) { st[i], st[j] = st[j], st[i] })
+        g.rng.Shuffle(len(st), func(i, j int) { st[i], st[j] = st[j], st[i] })
         for i, route := range g.table.Board.Routes {
             if route.StartToken {
                 g.table.Board.Routes[i].Token = st[0]
@@ -1723,10 +1955,8 @@ func (g *Game) checkStatus() {
         }
         g.table.PlayerBoards = newPb
 
-        // Randomize player order ([0] is start player)
-        rand.Shuffle(len(g.table.PlayerBoards), func(i, j int) {
-            g.table.PlayerBoards[i], g.table.PlayerBoards[j] = g.table.PlayerBoards[j], g.table.PlayerBoards[i]
-        })
+        // Pick player order ([0] is start player)
+        g.orderPlayerBoards()
 
         // Create clients for each player
         for _, pb := range g.table.PlayerBoards {
@@ -1739,7 +1969,9 @@ func (g *Game) checkStatus() {
                 }
             }
             if pb.Identity.Type == simple.IdentityTypeBot {
-                playerClient = g.bm.NewBot(pb.Identity, g.Id)
+                bot := g.bm.NewBot(pb.Identity, g.Id, g.difficulty[pb.Identity], g.options.BotPacing)
+                bot.SetWatched(g.watched)
+                playerClient = bot
             }
             if playerClient == nil {
                 playerClient = client.NewDisconnectedMultiWebClient(pb.Identity)
@@ -1754,27 +1986,27 @@ func (g *Game) checkStatus() {
         g.times.elapsed = []time.Duration{}
         g.scores = []int{}
         g.bonusroute = []bool{}
-        for i, _ := range g.table.PlayerBoards {
-            color := g.table.PlayerBoards[i].Color
-            g.table.PlayerBoards[i].Supply[0] = simple.Piece{color, simple.DiscShape}
-            for i2:=0;i2<11;i2++ {
-                if i2 < 5+i {
-                    g.table.PlayerBoards[i].Supply[i2+1] = simple.Piece{color, simple.CubeShape}
-                } else {
-                    g.table.PlayerBoards[i].Stock[i2-(5+i)] = simple.Piece{color, simple.CubeShape}
-                }
-            }
+        g.table.DealStartPieces()
+        for range g.table.PlayerBoards {
             g.times.elapsed = append(g.times.elapsed, time.Duration(0))
             g.scores = append(g.scores, 0)
             g.bonusroute = append(g.bonusroute, false)
         }
         g.times.running = time.Now()
 
+        order := []simple.Identity{}
+        for _, pb := range g.table.PlayerBoards {
+            order = append(order, pb.Identity)
+        }
+        g.infof("Turn order (%s): %v", simple.TurnOrderNames[g.options.TurnOrder], order)
+
         g.notify(message.Server{
             SType: message.NotifyStartGame,
             Time: time.Now(),
             Data: message.NotifyStartGameData{
                 Table: *g.table,
+                TurnOrder: g.options.TurnOrder,
+                Order: order,
             },
         })
 
@@ -1797,127 +2029,161 @@ func (g *Game) checkStatus() {
     }
 
     if g.status == Running && g.newStatus == Scoring {
-        g.finalscores = []map[simple.ScoreType]int{}
-        localTotals := map[int]int{}
-        for i:=0;i<len(g.table.PlayerBoards);i++ {
-            g.finalscores = append(g.finalscores, map[simple.ScoreType]int{})
-            localTotals[i] = 0
+        ms := g.endgameScoring()
+        if g.sim {
+            for _, m := range ms {
+                g.handleScoring(m)
+            }
+        } else {
+            // Spaced out so players can watch the scores add up.
+            for i, m := range ms {
+                innerCopy := m
+                time.AfterFunc(
+                    time.Millisecond * time.Duration(500) * time.Duration(i+10), func() {
+                    g.scoring <- innerCopy
+                })
+            }
         }
+    }
 
-        g.notify(message.Server{
-            SType: message.NotifyScoringBegin,
-            Time: time.Now(),
-            Data: message.NotifyScoringBeginData{},
-        })
-        ms := []message.Server{}
-
-        add := func(p int, t simple.ScoreType, s int) {
-            localTotals[p] = localTotals[p] + s
-            ms = append(ms, message.Server{
-                SType: message.NotifyEndgameScoring,
-                Time: time.Now(),
-                Data: message.NotifyEndgameScoringData{
-                    Player: p,
-                    Type: t,
-                    Score: s,
-                },
-            })
-        }
+    if g.status == Scoring && g.newStatus == Complete {
 
-        for i, s := range g.scores {
-            add(i, simple.GameScoreType, s)
-        }
+    }
 
+    // Bots never disconnect on their own; let the game be cleaned up once the
+    // humans leave.
+    if g.status == Running && g.newStatus == Abandoned {
         for i, pb := range g.table.PlayerBoards {
-            s := 0
-            if pb.GetActionCubes() == 0 {
-                s+=4
-            }
-            if pb.GetBookDiscs() == 0 {
-                s+=4
-            }
-            if pb.GetPriviledgeCubes() == 0 {
-                s+=4
-            }
-            if pb.GetBagCubes() == 0 {
-                s+=4
+            if pb.Identity.Type == simple.IdentityTypeBot {
+                g.disconnects[i] = true
             }
-            add(i, simple.BoardScoreType, s)
         }
+    }
+
+    g.status = g.newStatus
+}
+
+// Tells everyone scoring has begun and returns the scoring messages to send,
+// in order, ending with NotifyComplete.
+func (g *Game) endgameScoring() []message.Server {
+    g.finalscores = []map[simple.ScoreType]int{}
+    localTotals := map[int]int{}
+    for i:=0;i<len(g.table.PlayerBoards);i++ {
+        g.finalscores = append(g.finalscores, map[simple.ScoreType]int{})
+        localTotals[i] = 0
+    }
+
+    g.notify(message.Server{
+        SType: message.NotifyScoringBegin,
+        Time: time.Now(),
+        Data: message.NotifyScoringBeginData{},
+    })
+    ms := []message.Server{}
 
-        coellen := map[int]int{}
-        control := map[int]int{}
-        for i:=0;i<len(g.scores);i++ {
-            coellen[i] = 0
-            control[i] = 0
+    add := func(p int, t simple.ScoreType, s int) {
+        localTotals[p] = localTotals[p] + s
+        ms = append(ms, message.Server{
+            SType: message.NotifyEndgameScoring,
+            Time: time.Now(),
+            Data: message.NotifyEndgameScoringData{
+                Player: p,
+                Type: t,
+                Score: s,
+            },
+        })
+    }
+
+    for i, s := range g.scores {
+        add(i, simple.GameScoreType, s)
+    }
+
+    for i, pb := range g.table.PlayerBoards {
+        s := 0
+        if pb.GetActionCubes() == 0 {
+            s+=4
         }
-        for _, c := range g.table.Board.Cities {
-            controlC := c.GetControl()
-            if controlC != simple.NonePlayerColor {
-                p := g.colorToPlayer(controlC)
-                control[p] = control[p] + 2
-            }
-            if c.Coellen.Spots != nil {
-                for _, s := range c.Coellen.Spots {
-                    if s.Piece != (simple.Piece{}) {
-                        p := g.colorToPlayer(s.Piece.PlayerColor)
-                        coellen[p] = coellen[p] + s.Points
-                    }
-                }
-            }
+        if pb.GetBookDiscs() == 0 {
+            s+=4
         }
-        for p, s := range coellen {
-            add(p, simple.CoellenScoreType, s)
+        if pb.GetPriviledgeCubes() == 0 {
+            s+=4
         }
-        for p, s := range control {
-            add(p, simple.ControlScoreType, s)
+        if pb.GetBagCubes() == 0 {
+            s+=4
         }
-
-        for i, pb := range g.table.PlayerBoards {
-            keys := g.table.PlayerBoards[i].GetKeys()
-            add(i, simple.NetworkScoreType, g.table.Board.GetNetworkScore(pb.Color) * keys)
+        if g.frozen[i] {
+            s = 0
         }
+        add(i, simple.BoardScoreType, s)
+    }
 
-        localTotalsOrder := []int{}
-        for p, s := range localTotals {
-            add(p, simple.TotalScoreType, s)
-            localTotalsOrder = append(localTotalsOrder, p)
+    coellen := map[int]int{}
+    control := map[int]int{}
+    for i:=0;i<len(g.scores);i++ {
+        coellen[i] = 0
+        control[i] = 0
+    }
+    for _, c := range g.table.Board.Cities {
+        controlC := c.GetControl()
+        if controlC != simple.NonePlayerColor {
+            p := g.colorToPlayer(controlC)
+            if !g.frozen[p] {
+                control[p] = control[p] + 2
+            }
         }
-        sort.Slice(localTotalsOrder, func(i, j int) bool {
-            return localTotals[localTotalsOrder[i]] < localTotals[localTotalsOrder[j]]
-        })
-
-        for i, p := range localTotalsOrder {
-            add(p, simple.PlaceScoreType, len(localTotalsOrder)-1-i)
+        if c.Coellen.Spots != nil {
+            for _, s := range c.Coellen.Spots {
+                if s.Piece != (simple.Piece{}) {
+                    p := g.colorToPlayer(s.Piece.PlayerColor)
+                    if !g.frozen[p] {
+                        coellen[p] = coellen[p] + s.Points
+                    }
+                }
+            }
         }
-        ms = append(ms, message.Server{
-            SType: message.NotifyComplete,
-            Time: time.Now(),
-            Data: message.NotifyCompleteData{},
-        })
+    }
+    for p, s := range coellen {
+        add(p, simple.CoellenScoreType, s)
+    }
+    for p, s := range control {
+        add(p, simple.ControlScoreType, s)
+    }
 
-        for i, m := range ms {
-            innerCopy := m
-            time.AfterFunc(
-                time.Millisecond * time.Duration(500) * time.Duration(i+10), func() {
-                g.scoring <- innerCopy
-            })
+    for i, pb := range g.table.PlayerBoards {
+        keys := g.table.PlayerBoards[i].GetKeys()
+        if g.frozen[i] {
+            keys = 0
         }
+        add(i, simple.NetworkScoreType, g.table.Board.GetNetworkScore(pb.Color) * keys)
     }
 
-    if g.status == Scoring && g.newStatus == Complete {
-
+    for p, s := range localTotals {
+        add(p, simple.TotalScoreType, s)
     }
+    localTotalsOrder := g.rank(localTotals)
 
-    g.status = g.newStatus
+    for i, p := range localTotalsOrder {
+        add(p, simple.PlaceScoreType, len(localTotalsOrder)-1-i)
+    }
+    ms = append(ms, message.Server{
+        SType: message.NotifyComplete,
+        Time: time.Now(),
+        Data: message.NotifyCompleteData{},
+    })
+    return ms
 }
 
 func (g *Game) hotdeployLoad() {
     // TODO: this.
 }
 
+// Frozen players' scores are zeroed in ss so everyone sees the same thing.
 func (g *Game) updateScores(ss []int) {
     for i, s := range ss {
+        if g.frozen[i] {
+            ss[i] = 0
+            continue
+        }
         g.scores[i] += s
     }
 }
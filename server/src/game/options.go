@@ -0,0 +1,137 @@
+package game
+
+import (
+    "sort"
+    "time"
+    "local/hansa/client"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+func (g *Game) handleUpdateGameOptions(c client.Client, d message.UpdateGameOptionsData) {
+    if g.status != Creating {
+        g.clientError(c, "Options Error", "You can only change options when a game is 'Creating'")
+        return
+    }
+    if c.Identity() != g.Creator {
+        g.clientError(c, "Options Error", "Only the Creator (%s) can change options", g.Creator.Name)
+        return
+    }
+    if err := d.Options.Validate(); err != "" {
+        g.clientError(c, "Options Error", "%s", err)
+        return
+    }
+
+    g.debugf("Options updated: %+v", d.Options)
+    g.options = d.Options
+    g.notify(message.Server{
+        SType: message.NotifyGameOptions,
+        Time: time.Now(),
+        Data: message.NotifyGameOptionsData{
+            Options: g.options,
+        },
+    })
+}
+
+// A seated player takes a color from an empty seat, which gets theirs in
+// return.  Colors held by other seated players can't be taken.
+func (g *Game) handleRequestColor(c client.Client, d message.RequestColorData) {
+    if g.status != Creating {
+        g.clientError(c, "Color Error", "You can only choose a color when a game is 'Creating'")
+        return
+    }
+    if d.Color <= simple.NonePlayerColor || d.Color > simple.RedPlayerColor {
+        g.clientError(c, "Color Error", "Not a valid color: %d", d.Color)
+        return
+    }
+
+    seat := -1
+    other := -1
+    for i, pb := range g.table.PlayerBoards {
+        if pb.Identity == c.Identity() {
+            seat = i
+        }
+        if pb.Color == d.Color {
+            other = i
+        }
+    }
+    if seat == -1 {
+        g.clientError(c, "Color Error", "You must sit down before choosing a color")
+        return
+    }
+    if seat == other {
+        g.clientError(c, "Color Error", "You are already %s", simple.PlayerColorNames[d.Color])
+        return
+    }
+    if i := g.table.PlayerBoards[other].Identity; i != simple.EmptyIdentity {
+        g.clientError(c, "Color Error", "%s is already %s", i.Name, simple.PlayerColorNames[d.Color])
+        return
+    }
+
+    // Track pieces are colored, so these have to be new boards.
+    old := g.table.PlayerBoards[seat].Color
+    g.debugf("(%s) Chose %s", c.Identity(), simple.PlayerColorNames[d.Color])
+    g.table.PlayerBoards[seat] = simple.NewPlayerBoard(c.Identity(), d.Color)
+    g.table.PlayerBoards[other] = simple.NewPlayerBoard(simple.EmptyIdentity, old)
+    g.notify(message.Server{
+        SType: message.NotifyColor,
+        Time: time.Now(),
+        Data: message.NotifyColorData{
+            Identity: c.Identity(),
+            Index: seat,
+            Color: d.Color,
+            OtherIndex: other,
+            OtherColor: old,
+        },
+    })
+}
+
+// Called once empty boards are removed, so PlayerBoards is still in seat
+// order here.
+func (g *Game) orderPlayerBoards() {
+    pbs := g.table.PlayerBoards
+    switch g.options.TurnOrder {
+        case simple.SeatTurnOrder:
+            return
+        case simple.ReverseRankTurnOrder:
+            if len(g.lastRanking) > 0 {
+                break
+            }
+            g.debugf("No previous ranking, using a random turn order")
+            fallthrough
+        default:
+            g.rng.Shuffle(len(pbs), func(i, j int) {
+                pbs[i], pbs[j] = pbs[j], pbs[i]
+            })
+            return
+    }
+
+    // Worst of the last game goes first.  Anyone who didn't play in it goes
+    // before all of them, in random order.
+    rank := map[simple.Identity]int{}
+    for i, identity := range g.lastRanking {
+        rank[identity] = i
+    }
+    g.rng.Shuffle(len(pbs), func(i, j int) {
+        pbs[i], pbs[j] = pbs[j], pbs[i]
+    })
+    sort.SliceStable(pbs, func(i, j int) bool {
+        ri, iok := rank[pbs[i].Identity]
+        rj, jok := rank[pbs[j].Identity]
+        if iok != jok {
+            return !iok
+        }
+        return ri > rj
+    })
+}
+
+// Used by the lobby before Run for matched games, whose players have already
+// agreed to play.
+func (g *Game) Autostart() {
+    g.newStatus = Running
+}
+
+// Used by the lobby before Run when this game is a rematch.
+func (g *Game) SetLastRanking(ranking []simple.Identity) {
+    g.lastRanking = ranking
+}
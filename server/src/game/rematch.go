@@ -0,0 +1,163 @@
+package game
+
+import (
+    "time"
+    "local/hansa/client"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+const rematchTimeout = 30 * time.Second
+
+// The lobby owns game creation, so a Complete game asks it for a rematch.
+type Lobby interface {
+    Rematch(r Rematch)
+}
+
+// A request for a new game with the same seats and options.  Seats is
+// indexed by seat (Color-1), with simple.EmptyIdentity for anyone who
+// declined.  Ranking is everyone from the finished game, best to worst.
+// Difficulty is for the bots in Seats.  The lobby sends the new game id back
+// on Id (which must be buffered).
+type Rematch struct {
+    Creator simple.Identity
+    Options simple.GameOptions
+    Seats []simple.Identity
+    Difficulty map[simple.Identity]simple.Difficulty
+    Ranking []simple.Identity
+    Id chan int
+}
+
+type rematchVote struct {
+    requester simple.Identity
+    deadline time.Time
+    answers map[int]bool // player -> accepted
+    requested bool // we have asked the lobby and are waiting on an id
+    id int // the new game, once created
+}
+
+func (g *Game) handleRequestRematch(p int, c client.Client, d message.RequestRematchData) {
+    if g.status != Complete {
+        g.clientError(c, "Rematch Error", "You can only ask for a rematch when the game is 'Complete'")
+        return
+    }
+    if g.lobby == nil {
+        g.clientError(c, "Rematch Error", "This game can not create rematches")
+        return
+    }
+    if g.rematch != nil {
+        if g.rematch.id != 0 {
+            g.clientError(c, "Rematch Error", "The rematch was already created (game %d)", g.rematch.id)
+        } else {
+            g.clientError(c, "Rematch Error", "A rematch was already proposed by %s", g.rematch.requester.Name)
+        }
+        return
+    }
+
+    g.debugf("(%s) Requested a rematch", c.Identity())
+    g.rematch = &rematchVote{
+        requester: c.Identity(),
+        deadline: time.Now().Add(rematchTimeout),
+        answers: map[int]bool{p: true},
+    }
+
+    // Bots are always up for another game.
+    for i, pb := range g.table.PlayerBoards {
+        if pb.Identity.Type == simple.IdentityTypeBot {
+            g.rematch.answers[i] = true
+        }
+    }
+
+    g.notify(message.Server{
+        SType: message.NotifyRematchRequest,
+        Time: time.Now(),
+        Data: message.NotifyRematchRequestData{
+            Requester: c.Identity(),
+            Deadline: g.rematch.deadline,
+        },
+    })
+    time.AfterFunc(rematchTimeout, func() {
+        g.timeouts <- RematchTimeoutType
+    })
+    g.checkRematch(false)
+}
+
+func (g *Game) handleAnswerRematch(p int, c client.Client, d message.AnswerRematchData) {
+    if g.rematch == nil || g.rematch.requested {
+        g.clientError(c, "Rematch Error", "There is no rematch waiting on an answer")
+        return
+    }
+    if _, ok := g.rematch.answers[p]; ok {
+        g.clientError(c, "Rematch Error", "You already answered")
+        return
+    }
+
+    g.debugf("(%s) Answered rematch: %t", c.Identity(), d.Accept)
+    g.rematch.answers[p] = d.Accept
+    g.checkRematch(false)
+}
+
+// Once everyone has answered (or force, on timeout), ask the lobby for the new
+// game.  Players who did not answer are treated as declining.
+func (g *Game) checkRematch(force bool) {
+    if g.rematch == nil || g.rematch.requested {
+        return
+    }
+    if !force && len(g.rematch.answers) < len(g.players) {
+        return
+    }
+
+    creator := g.rematch.requester
+    seats := []simple.Identity{}
+    for i:=0;i<5;i++ {
+        seats = append(seats, simple.EmptyIdentity)
+    }
+    for i, pb := range g.table.PlayerBoards {
+        if !g.rematch.answers[i] {
+            continue
+        }
+        seats[int(pb.Color)-1] = pb.Identity
+        if pb.Identity == g.Creator {
+            creator = g.Creator
+        }
+    }
+
+    ranking := make([]simple.Identity, len(g.table.PlayerBoards))
+    for i, pb := range g.table.PlayerBoards {
+        ranking[g.finalscores[i][simple.PlaceScoreType]] = pb.Identity
+    }
+
+    g.debugf("Asking lobby for a rematch (creator %s)", creator)
+    g.rematch.requested = true
+    g.lobby.Rematch(Rematch{
+        Creator: creator,
+        Options: g.options,
+        Seats: seats,
+        Difficulty: g.difficulty,
+        Ranking: ranking,
+        Id: g.rematchId,
+    })
+}
+
+func (g *Game) handleRematchId(id int) {
+    g.debugf("Rematch created: game %d", id)
+    g.rematch.id = id
+    g.notify(message.Server{
+        SType: message.NotifyRematch,
+        Time: time.Now(),
+        Data: message.NotifyRematchData{
+            Id: id,
+        },
+    })
+}
+
+// Used by the lobby before Run to carry seats over from a rematch.  Bots not
+// in difficulty play at their default.
+func (g *Game) Preseat(seats []simple.Identity, difficulty map[simple.Identity]simple.Difficulty) {
+    for i, s := range seats {
+        g.table.PlayerBoards[i].Identity = s
+        if d, ok := difficulty[s]; ok {
+            g.difficulty[s] = d
+        }
+    }
+}
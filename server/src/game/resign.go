@@ -0,0 +1,212 @@
+package game
+
+import (
+    "sort"
+    "time"
+    "local/hansa/client"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// A player may resign at any point in a Running game except in the middle of
+// their own action or bump response.  With a BotId the bot takes over the
+// seat; otherwise the player is frozen: their score no longer changes, their
+// turns are skipped, bumps against them resolve automatically, and they are
+// ranked last.
+func (g *Game) handleResign(p int, c client.Client, d message.ResignData) {
+    if g.status != Running || g.newStatus != Running {
+        g.clientError(c, "Resign Error", "You can only resign when a game is 'Running'")
+        return
+    }
+    if g.turnState.Type == simple.Bumping && g.turnState.BumpingPlayer == p {
+        g.clientError(c, "Resign Error", "Finish responding to the bump first")
+        return
+    }
+    if g.turnState.Player == p && g.turnState.Type != simple.NoneTurnStateType &&
+        g.turnState.Type != simple.Bumping {
+        g.clientError(c, "Resign Error", "Finish your current action first")
+        return
+    }
+
+    replacement := simple.EmptyIdentity
+    if d.BotId != "" {
+        replacement = g.bm.GetIdentity(d.BotId)
+        if replacement == simple.EmptyIdentity {
+            g.clientError(c, "Resign Error", "Not a valid Bot ID: '%s'", d.BotId)
+            return
+        }
+        for _, pb := range g.table.PlayerBoards {
+            if pb.Identity == replacement {
+                g.clientError(c, "Resign Error", "%s is already playing in this game", replacement.Name)
+                return
+            }
+        }
+    }
+
+    g.infof("(%s) Resigned (replacement: %s)", c.Identity(), replacement)
+    if replacement == simple.EmptyIdentity {
+        g.frozen[p] = true
+        delete(g.abandonVotes, p)
+    } else {
+        // The human keeps watching as an observer.
+        if mc, ok := c.(*client.MultiWebClient); ok {
+            g.observers[c.Identity()] = mc
+        }
+        delete(g.disconnects, p)
+        delete(g.abandonVotes, p)
+        g.table.PlayerBoards[p].Identity = replacement
+        bot := g.bm.NewBot(replacement, g.Id, simple.NoneDifficulty, g.options.BotPacing)
+        bot.SetWatched(g.watched)
+        g.players[p] = &Player{
+            Client: bot,
+        }
+        bot.Send(g.fullGame())
+    }
+
+    g.notify(message.Server{
+        SType: message.NotifyResign,
+        Time: time.Now(),
+        Data: message.NotifyResignData{
+            Player: p,
+            Identity: c.Identity(),
+            Replacement: replacement,
+        },
+    })
+
+    if g.activeHumans() == 0 {
+        g.debugf("No humans left playing, abandoning")
+        g.abandon()
+        return
+    }
+    g.checkAbandonVotes()
+}
+
+func (g *Game) handleAbandonVote(p int, c client.Client, d message.AbandonVoteData) {
+    if g.status != Running || g.newStatus != Running {
+        g.clientError(c, "Abandon Error", "You can only vote to abandon a 'Running' game")
+        return
+    }
+    if !isHuman(c.Identity()) {
+        g.clientError(c, "Abandon Error", "Only human players may vote to abandon")
+        return
+    }
+
+    if d.Abandon {
+        g.abandonVotes[p] = true
+    } else {
+        delete(g.abandonVotes, p)
+    }
+    g.debugf("(%s) Abandon vote: %t", c.Identity(), d.Abandon)
+    g.notify(message.Server{
+        SType: message.NotifyAbandonVote,
+        Time: time.Now(),
+        Data: message.NotifyAbandonVoteData{
+            Identity: c.Identity(),
+            Abandon: d.Abandon,
+            Votes: len(g.abandonVotes),
+            Needed: g.activeHumans()/2 + 1,
+        },
+    })
+    g.checkAbandonVotes()
+}
+
+// A strict majority of the humans still playing ends the game.
+func (g *Game) checkAbandonVotes() {
+    if len(g.abandonVotes) > 0 && len(g.abandonVotes) >= g.activeHumans()/2 + 1 {
+        g.debugf("Abandon vote passed (%d votes)", len(g.abandonVotes))
+        g.abandon()
+    }
+}
+
+// Records the current scores as final (frozen players ranked last) and moves
+// us to Abandoned.
+func (g *Game) abandon() {
+    g.finalscores = []map[simple.ScoreType]int{}
+    totals := map[int]int{}
+    for i, s := range g.scores {
+        g.finalscores = append(g.finalscores, map[simple.ScoreType]int{
+            simple.GameScoreType: s,
+            simple.TotalScoreType: s,
+        })
+        totals[i] = s
+    }
+    for i, p := range g.rank(totals) {
+        g.finalscores[p][simple.PlaceScoreType] = len(g.players)-1-i
+    }
+
+    g.newStatus = Abandoned
+    g.times.complete = time.Now()
+    g.notify(message.Server{
+        SType: message.NotifyAbandoned,
+        Time: time.Now(),
+        Data: message.NotifyAbandonedData{
+            Scores: g.finalscores,
+        },
+    })
+}
+
+// Frozen players can't act, so we act for them until someone who can is up:
+// a bump against them moves the bumped piece to the first valid spot without
+// replacing, and their turns end immediately.
+func (g *Game) playFrozen() {
+    for g.status == Running && g.newStatus == Running {
+        ts := g.turnState
+        if ts.Type == simple.Bumping && g.frozen[ts.BumpingPlayer] {
+            p := ts.BumpingPlayer
+            c := g.players[p].Client
+            if !ts.BumpingMoved {
+                valid := g.table.ValidBumps(ts.BumpingLocation)
+                if len(valid) == 0 {
+                    g.errorf("No valid bump spots for frozen player %d", p)
+                    return
+                }
+                g.handleDoSubaction(p, c, simple.Subaction{
+                    Source: ts.BumpingLocation,
+                    Dest: valid[0],
+                    Piece: g.table.GetPiece(ts.BumpingLocation),
+                })
+                if !g.turnState.BumpingMoved {
+                    g.errorf("Unable to move bumped piece for frozen player %d", p)
+                    return
+                }
+            }
+            g.handleEndBump(p, c, message.EndBumpData{})
+            continue
+        }
+        if ts.Type == simple.NoneTurnStateType && g.frozen[ts.Player] {
+            g.handleEndTurn(ts.Player, g.players[ts.Player].Client, message.EndTurnData{})
+            continue
+        }
+        return
+    }
+}
+
+func (g *Game) activeHumans() int {
+    n := 0
+    for i, pb := range g.table.PlayerBoards {
+        if isHuman(pb.Identity) && !g.frozen[i] {
+            n++
+        }
+    }
+    return n
+}
+
+// Players ordered from worst to best by total, with frozen players always
+// worse than everyone else.
+func (g *Game) rank(totals map[int]int) []int {
+    r := []int{}
+    for p, _ := range totals {
+        r = append(r, p)
+    }
+    sort.Slice(r, func(i, j int) bool {
+        if g.frozen[r[i]] != g.frozen[r[j]] {
+            return g.frozen[r[i]]
+        }
+        return totals[r[i]] < totals[r[j]]
+    })
+    return r
+}
+
+func isHuman(i simple.Identity) bool {
+    return i.Type == simple.IdentityTypeConnection || i.Type == simple.IdentityTypeGuest
+}
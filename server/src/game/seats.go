@@ -0,0 +1,158 @@
+package game
+
+import (
+    "time"
+    "local/hansa/client"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+const readyCheckTimeout = 20 * time.Second
+
+// Creator only: empties a seat, whether it's a human or a bot in it.
+func (g *Game) handleKickSeat(c client.Client, d message.KickSeatData) {
+    if g.status != Creating {
+        g.clientError(c, "Kick Error", "You can only kick when a game is 'Creating'")
+        return
+    }
+    if c.Identity() != g.Creator {
+        g.clientError(c, "Kick Error", "Only the Creator (%s) can kick", g.Creator.Name)
+        return
+    }
+    if d.Index < 0 || d.Index >= 5 {
+        g.clientError(c, "Kick Error", "Seat does not exist: %d", d.Index)
+        return
+    }
+    i := g.table.PlayerBoards[d.Index].Identity
+    if i == simple.EmptyIdentity {
+        g.clientError(c, "Kick Error", "Nobody is sitting there")
+        return
+    }
+    if i == g.Creator {
+        g.clientError(c, "Kick Error", "Stand up instead of kicking yourself")
+        return
+    }
+
+    g.debugf("(%s) Kicked %s", c.Identity(), i)
+    g.vacate(d.Index)
+}
+
+// Creator only: a locked seat can't be taken by a human (bots may still be
+// added there).  Locking doesn't remove whoever is already sitting.
+func (g *Game) handleLockSeat(c client.Client, d message.LockSeatData) {
+    if g.status != Creating {
+        g.clientError(c, "Lock Error", "You can only lock seats when a game is 'Creating'")
+        return
+    }
+    if c.Identity() != g.Creator {
+        g.clientError(c, "Lock Error", "Only the Creator (%s) can lock seats", g.Creator.Name)
+        return
+    }
+    if d.Index < 0 || d.Index >= 5 {
+        g.clientError(c, "Lock Error", "Seat does not exist: %d", d.Index)
+        return
+    }
+
+    g.debugf("(%s) Lock seat %d: %t", c.Identity(), d.Index, d.Lock)
+    if d.Lock {
+        g.locked[d.Index] = true
+    } else {
+        delete(g.locked, d.Index)
+    }
+    g.notify(message.Server{
+        SType: message.NotifyLockSeat,
+        Time: time.Now(),
+        Data: message.NotifyLockSeatData{
+            Index: d.Index,
+            Lock: d.Lock,
+        },
+    })
+}
+
+func (g *Game) handleReady(c client.Client, d message.ReadyData) {
+    if g.status != ReadyCheck {
+        g.clientError(c, "Ready Error", "There is no ready check in progress")
+        return
+    }
+    index := -1
+    for i, pb := range g.table.PlayerBoards {
+        if pb.Identity == c.Identity() {
+            index = i
+        }
+    }
+    if index == -1 {
+        g.clientError(c, "Ready Error", "You are not sitting at this game")
+        return
+    }
+    if g.ready[index] {
+        return
+    }
+
+    g.debugf("(%s) Ready", c.Identity())
+    g.ready[index] = true
+    g.notify(message.Server{
+        SType: message.NotifyReady,
+        Time: time.Now(),
+        Data: message.NotifyReadyData{
+            Identity: c.Identity(),
+            Index: index,
+        },
+    })
+    if len(g.unready()) == 0 {
+        g.newStatus = Running
+    }
+}
+
+// Unready seats are vacated; we start if there are still enough players, and
+// go back to Creating otherwise.
+func (g *Game) handleReadyCheckTimeout() {
+    if g.status != ReadyCheck || g.newStatus != ReadyCheck || time.Now().Before(g.readyDeadline) {
+        return
+    }
+    for _, i := range g.unready() {
+        g.debugf("(%s) Not ready in time", g.table.PlayerBoards[i].Identity)
+        g.vacate(i)
+    }
+    if g.seated() >= 4 {
+        g.newStatus = Running
+        return
+    }
+    g.debugf("Not enough players ready, back to Creating")
+    g.newStatus = Creating
+}
+
+// Seated humans who haven't sent Ready.
+func (g *Game) unready() []int {
+    r := []int{}
+    for i, pb := range g.table.PlayerBoards {
+        if isHuman(pb.Identity) && !g.ready[i] {
+            r = append(r, i)
+        }
+    }
+    return r
+}
+
+func (g *Game) seated() int {
+    n := 0
+    for _, pb := range g.table.PlayerBoards {
+        if pb.Identity != simple.EmptyIdentity {
+            n++
+        }
+    }
+    return n
+}
+
+func (g *Game) vacate(index int) {
+    i := g.table.PlayerBoards[index].Identity
+    g.table.PlayerBoards[index].Identity = simple.EmptyIdentity
+    delete(g.difficulty, i)
+    g.notify(message.Server{
+        SType: message.NotifySitdown,
+        Time: time.Now(),
+        Data: message.NotifySitdownData{
+            Identity: i,
+            Index: index,
+            Sitdown: false,
+        },
+    })
+}
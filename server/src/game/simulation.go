@@ -0,0 +1,346 @@
+package game
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "math/rand"
+    "path/filepath"
+    "runtime/debug"
+    "sync"
+    "time"
+    "local/hansa/bot"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// A game played start to finish by random bots (bot.RandomBrain) against the
+// real Game, with no clients, database or lobby, to shake out panics and
+// stalls.  Everything random comes from Seed, so running the same Seed again
+// replays the same game (short of goroutine scheduling; the moves are in the
+// result too, see Replay).
+type SimulationConfig struct {
+    Seed int64
+    Players int // 4-5, as StartGame allows
+
+    // Limits on a game that has stopped making sense.
+    MaxMessages int
+    StallTimeout time.Duration
+
+    // If set, the bots are RouteBrains playing each other instead, and a
+    // completed game comes back with every plan they chose (see
+    // bot.Decision), for training.
+    SelfPlay bool
+
+    // If set too, the bots play this Model (see bot.LoadModel) instead.
+    Model string
+}
+
+type SimulationStatus string
+const (
+    SimulationComplete SimulationStatus = "Complete"
+    SimulationPanic SimulationStatus = "Panic" // in the game or a bot
+    SimulationStall SimulationStatus = "Stall" // nobody moved for StallTimeout
+    SimulationTooLong SimulationStatus = "TooLong" // over MaxMessages
+    SimulationBroken SimulationStatus = "Broken" // the table broke an invariant
+    SimulationInvalid SimulationStatus = "Invalid" // a bad SimulationConfig
+)
+
+type SimulationResult struct {
+    Seed int64
+    Players int
+    Status SimulationStatus
+    Panic string // or why it was Invalid
+    Stack string
+    Scores []int
+    Moves []SimulatedMove
+
+    // Broken only: what was wrong (see Table.CheckInvariants), and the table
+    // right after the subaction that broke it.
+    Violations []string
+    Table *simple.Table
+
+    // SelfPlay only, once complete.
+    Decisions []bot.Decision
+    Features *bot.FeatureNames
+}
+
+// One message a bot sent, in the order the game handled them.
+type SimulatedMove struct {
+    Player int
+    Type string
+    Message message.Client
+}
+
+type simulationPanic struct {
+    what string
+    stack string
+}
+
+func (r SimulationResult) Failed() bool {
+    return r.Status != SimulationComplete
+}
+
+// Writes the result to dir/sim-<seed>.json and returns the path.
+func (r SimulationResult) Dump(dir string) (string, error) {
+    bytes, err := json.MarshalIndent(r, "", "  ")
+    if err != nil {
+        return "", err
+    }
+    path := filepath.Join(dir, fmt.Sprintf("sim-%d.json", r.Seed))
+    return path, ioutil.WriteFile(path, bytes, 0644)
+}
+
+// Reads a result Dump wrote.
+func LoadSimulation(path string) (SimulationResult, error) {
+    var r SimulationResult
+    bytes, err := ioutil.ReadFile(path)
+    if err != nil {
+        return r, err
+    }
+    if err = json.Unmarshal(bytes, &r); err != nil {
+        return r, err
+    }
+
+    // Data comes back a map; the game wants the real type.
+    for i, m := range r.Moves {
+        bytes, err = json.Marshal(m.Message)
+        if err != nil {
+            return r, err
+        }
+        if r.Moves[i].Message, err = message.UnmarshalClient(bytes); err != nil {
+            return r, fmt.Errorf("move %d: %s", i, err)
+        }
+    }
+    return r, nil
+}
+
+// Autostart skips StartGame, so we check its player count here.
+func (c SimulationConfig) validate() string {
+    if c.Players < 4 || c.Players > 5 {
+        return fmt.Sprintf("%d players, but only 4-5 is supported", c.Players)
+    }
+    if c.Model != "" && !c.SelfPlay {
+        return "only SelfPlay bots play a Model"
+    }
+    return ""
+}
+
+// A started Game from seed, with its seats named after name and its bots from
+// bm.
+func newSimulatedGame(seed int64, players int, name string, bm *bot.Manager) *Game {
+    g := New(int(seed), simple.EmptyIdentity, simple.GameOptions{}, nil, nil, bm, nil)
+    g.rng = rand.New(rand.NewSource(seed))
+    g.sim = true
+    g.checkTable = true
+    seats := []simple.Identity{}
+    for i:=1;i<=players;i++ {
+        seats = append(seats, simple.NewBotIdentity(fmt.Sprintf("%c%d", name[0], i), fmt.Sprintf("%s %d (Bot)", name, i)))
+    }
+    g.Preseat(seats, nil)
+    g.Autostart()
+    return g
+}
+
+// Calls f on the game's goroutine, turning a panic into an error.
+func simulationStep(f func()) (err *simulationPanic) {
+    defer func() {
+        if p := recover(); p != nil {
+            err = &simulationPanic{fmt.Sprintf("game: %v", p), string(debug.Stack())}
+        }
+    }()
+    f()
+    return nil
+}
+
+func Simulate(c SimulationConfig) (r SimulationResult) {
+    r = SimulationResult{
+        Seed: c.Seed,
+        Players: c.Players,
+        Moves: []SimulatedMove{},
+    }
+    if err := c.validate(); err != "" {
+        r.Status = SimulationInvalid
+        r.Panic = err
+        return r
+    }
+
+    // Bots panic on their own goroutines, and the game then waits on them
+    // forever; the watchdog below wakes it up to notice.
+    var botPanicLock sync.Mutex
+    var botPanic *simulationPanic
+    recovered := func(i simple.Identity, p interface{}) {
+        botPanicLock.Lock()
+        defer botPanicLock.Unlock()
+        if botPanic == nil {
+            botPanic = &simulationPanic{fmt.Sprintf("bot %s: %v", i, p), string(debug.Stack())}
+        }
+    }
+
+    var decisionsLock sync.Mutex
+    decisions := []bot.Decision{}
+    bm := bot.NewRandomManager(c.Seed, recovered)
+    name := "Random"
+    if c.SelfPlay {
+        bm = bot.NewSelfPlayManager(c.Seed, recovered, func(d bot.Decision) {
+            decisionsLock.Lock()
+            defer decisionsLock.Unlock()
+            decisions = append(decisions, d)
+        })
+        name = "Self"
+        if c.Model != "" {
+            if err := bm.SelfPlayModel(c.Model); err != nil {
+                r.Status = SimulationInvalid
+                r.Panic = err.Error()
+                return r
+            }
+        }
+    }
+
+    g := newSimulatedGame(c.Seed, c.Players, name, bm)
+    lastMove := time.Now()
+    g.record = func(p int, m message.Client) {
+        lastMove = time.Now()
+        r.Moves = append(r.Moves, SimulatedMove{
+            Player: p,
+            Type: message.CTypeNames[m.CType],
+            Message: m,
+        })
+    }
+
+    done := make(chan struct{})
+    defer close(done)
+    go func() {
+        t := time.NewTicker(c.StallTimeout / 4)
+        defer t.Stop()
+        for {
+            select {
+                case <-done:
+                    return
+                case <-t.C:
+                    select {
+                        case g.timeouts <- NoneTimeoutType:
+                        default:
+                    }
+            }
+        }
+    }()
+    defer stopSimulatedBots(g)
+
+    step := func() {
+        g.checkStatus()
+        if g.status == Complete || g.status == Abandoned {
+            return
+        }
+        g.handleMsg()
+    }
+
+    for {
+        err := simulationStep(step)
+        botPanicLock.Lock()
+        if err == nil {
+            err = botPanic
+        }
+        botPanicLock.Unlock()
+
+        switch {
+            case err != nil:
+                r.Status = SimulationPanic
+                r.Panic = err.what
+                r.Stack = err.stack
+            case g.broken != nil:
+                r.Status = SimulationBroken
+                r.Violations = g.broken
+                table := g.table.Clone()
+                r.Table = &table
+            case g.status == Complete || g.status == Abandoned:
+                r.Status = SimulationComplete
+                r.Scores = g.scores
+                if c.SelfPlay {
+                    decisionsLock.Lock()
+                    r.Decisions = placeDecisions(decisions, g.scores)
+                    decisionsLock.Unlock()
+                    features := bot.NewFeatureNames(g.table)
+                    r.Features = &features
+                }
+            case len(r.Moves) > c.MaxMessages:
+                r.Status = SimulationTooLong
+            case time.Since(lastMove) > c.StallTimeout:
+                r.Status = SimulationStall
+            default:
+                continue
+        }
+        return r
+    }
+}
+
+// Plays r.Moves in order against a fresh Game from r.Seed whose bots never
+// answer, so a failed simulation still reproduces after the bots change.
+// Moves are what was played: up to the one that panicked or broke the table
+// (Panic or Broken), or all of them.  A game they don't finish is a Stall.
+func Replay(r SimulationResult) (replayed SimulationResult) {
+    replayed = SimulationResult{
+        Seed: r.Seed,
+        Players: r.Players,
+        Moves: []SimulatedMove{},
+    }
+    if err := (SimulationConfig{Seed: r.Seed, Players: r.Players}).validate(); err != "" {
+        replayed.Status = SimulationInvalid
+        replayed.Panic = err
+        return replayed
+    }
+
+    g := newSimulatedGame(r.Seed, r.Players, "Replay", bot.NewSilentManager())
+    defer stopSimulatedBots(g)
+    err := simulationStep(g.checkStatus)
+    for _, m := range r.Moves {
+        if err != nil || g.broken != nil || g.status == Complete || g.status == Abandoned {
+            break
+        }
+        if m.Player < 0 || m.Player >= len(g.players) {
+            replayed.Status = SimulationInvalid
+            replayed.Panic = fmt.Sprintf("move %d is by player %d", len(replayed.Moves), m.Player)
+            return replayed
+        }
+        replayed.Moves = append(replayed.Moves, m)
+        err = simulationStep(func() {
+            g.handlePlayerMsg(m.Player, g.players[m.Player], m.Message)
+            g.checkStatus()
+        })
+    }
+
+    switch {
+        case err != nil:
+            replayed.Status = SimulationPanic
+            replayed.Panic = err.what
+            replayed.Stack = err.stack
+        case g.broken != nil:
+            replayed.Status = SimulationBroken
+            replayed.Violations = g.broken
+            table := g.table.Clone()
+            replayed.Table = &table
+        case g.status == Complete || g.status == Abandoned:
+            replayed.Status = SimulationComplete
+            replayed.Scores = g.scores
+        default:
+            replayed.Status = SimulationStall
+    }
+    return replayed
+}
+
+func stopSimulatedBots(g *Game) {
+    for _, p := range g.players {
+        if b, ok := p.Client.(*bot.Bot); ok {
+            b.Done()
+        }
+    }
+}
+
+func placeDecisions(decisions []bot.Decision, scores []int) []bot.Decision {
+    places := bot.Places(scores)
+    for i, _ := range decisions {
+        decisions[i].Places = places
+        decisions[i].Place = places[decisions[i].Player]
+    }
+    return decisions
+}
@@ -0,0 +1,203 @@
+package game
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "time"
+    "local/hansa/bot"
+    "local/hansa/log"
+)
+
+// A dumped game replays to the same end, so a failure keeps reproducing.
+func TestReplayDump(t *testing.T) {
+    log.Init(t.TempDir(), log.InfoLevel)
+    r := Simulate(SimulationConfig{
+        Seed: 3,
+        Players: 4,
+        MaxMessages: 50000,
+        StallTimeout: 10 * time.Second,
+    })
+    if r.Failed() {
+        t.Fatalf("simulation %s: %s", r.Status, r.Panic)
+    }
+    path, err := r.Dump(t.TempDir())
+    if err != nil {
+        t.Fatal(err)
+    }
+    loaded, err := LoadSimulation(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    replayed := Replay(loaded)
+    if replayed.Status != SimulationComplete || !reflect.DeepEqual(replayed.Scores, r.Scores) {
+        t.Errorf("replay of %s: %s with scores %v, want %v", filepath.Base(path), replayed.Status, replayed.Scores, r.Scores)
+    }
+
+    // Moves that stop short leave the game waiting.
+    loaded.Moves = loaded.Moves[:len(loaded.Moves)/2]
+    if replayed = Replay(loaded); replayed.Status != SimulationStall {
+        t.Errorf("half a game replayed %s, want %s", replayed.Status, SimulationStall)
+    }
+}
+
+func TestSimulatePlayers(t *testing.T) {
+    for _, p := range []int{0, 2, 3, 6} {
+        if r := Simulate(SimulationConfig{Seed: 1, Players: p}); r.Status != SimulationInvalid {
+            t.Errorf("%d players: %s, want %s", p, r.Status, SimulationInvalid)
+        }
+    }
+}
+
+// Self-play games that once failed, each with what went wrong.
+func TestSelfPlayRegressions(t *testing.T) {
+    log.Init(t.TempDir(), log.InfoLevel)
+    tests := []struct{
+        seed int64
+        players int
+        why string
+    }{
+        {7, 4, "a Points plan cleared without taking the award, and the rejected EndTurn went ignored"},
+        {28, 4, "two leftover moves went to the same spot"},
+        {10, 5, "two leftover moves went to the same spot"},
+    }
+    for _, test := range tests {
+        r := Simulate(SimulationConfig{
+            Seed: test.seed,
+            Players: test.players,
+            MaxMessages: 50000,
+            StallTimeout: 10 * time.Second,
+            SelfPlay: true,
+        })
+        if r.Status != SimulationComplete {
+            t.Errorf("seed %d (%s): %s after %d moves %s", test.seed, test.why, r.Status, len(r.Moves), r.Panic)
+        }
+    }
+}
+
+func selfPlay(seed int64, players int, model string) SimulationResult {
+    return Simulate(SimulationConfig{
+        Seed: seed,
+        Players: players,
+        MaxMessages: 50000,
+        StallTimeout: 10 * time.Second,
+        SelfPlay: true,
+        Model: model,
+    })
+}
+
+// A few games each way, every decision recorded the way its features say.
+func TestSelfPlay(t *testing.T) {
+    log.Init(t.TempDir(), log.InfoLevel)
+    for i, seed := range []int64{1, 2, 3} {
+        r := selfPlay(seed, 4 + i%2, "")
+        if r.Status != SimulationComplete {
+            t.Errorf("seed %d: %s after %d moves %s", seed, r.Status, len(r.Moves), r.Panic)
+            continue
+        }
+        if len(r.Decisions) == 0 || r.Features == nil {
+            t.Errorf("seed %d: %d decisions, features %v", seed, len(r.Decisions), r.Features)
+            continue
+        }
+        for _, d := range r.Decisions {
+            if len(d.Table) != len(r.Features.Table) || len(d.Plans) == 0 ||
+                len(d.Plans[0].Features) != len(r.Features.Plan) {
+                t.Errorf("seed %d: decision %d of player %d doesn't match its features", seed, d.Number, d.Player)
+                break
+            }
+        }
+    }
+}
+
+// A dataset and its features, written and read back, train a model that
+// ModelBrains then play.
+func TestModelRoundTrip(t *testing.T) {
+    log.Init(t.TempDir(), log.InfoLevel)
+    dir := t.TempDir()
+    r := selfPlay(5, 4, "")
+    if r.Status != SimulationComplete {
+        t.Fatalf("self-play: %s %s", r.Status, r.Panic)
+    }
+
+    dataset := filepath.Join(dir, "selfplay.jsonl")
+    f, err := os.Create(dataset)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := bot.WriteDecisions(f, r.Decisions); err != nil {
+        t.Fatal(err)
+    }
+    f.Close()
+    if err := bot.WriteFeatureNames(dataset + ".features.json", map[string]bot.FeatureNames{r.Features.Board: *r.Features}); err != nil {
+        t.Fatal(err)
+    }
+
+    f, err = os.Open(dataset)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+    decisions, err := bot.ReadDecisions(f)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Times come back without their location, so compare what they encode.
+    written, _ := json.Marshal(r.Decisions)
+    read, _ := json.Marshal(decisions)
+    if string(read) != string(written) {
+        t.Fatalf("read back %d decisions that differ from the %d written", len(decisions), len(r.Decisions))
+    }
+    features, err := bot.LoadFeatureNames(dataset + ".features.json")
+    if err != nil {
+        t.Fatal(err)
+    }
+    names, ok := features[r.Features.Board]
+    if !ok {
+        t.Fatalf("no features for board %s in %v", r.Features.Board, features)
+    }
+
+    // Start from the plans' own values and nudge each feature by how much
+    // more the winners' chosen plans had of it than the plans they passed on;
+    // far enough and bots shuffle pieces back and forth forever.
+    model := bot.Model{Name: "roundtrip", FeatureVersion: names.Version, Plan: map[string]float64{}}
+    nudges := map[string]float64{}
+    n := 0
+    for _, d := range decisions {
+        if d.Kind != bot.PlanDecision || d.Place != 0 || len(d.Plans) < 2 {
+            continue
+        }
+        n++
+        for i, p := range d.Plans {
+            for j, v := range p.Features {
+                if i == 0 {
+                    nudges[names.Plan[j]] += v
+                } else {
+                    nudges[names.Plan[j]] -= v / float64(len(d.Plans) - 1)
+                }
+            }
+        }
+    }
+    if n == 0 {
+        t.Fatalf("no decisions by a winner in %d", len(decisions))
+    }
+    for name, v := range nudges {
+        model.Plan[name] = v / float64(n) / 100
+    }
+    model.Plan["logValue"] += 1
+    bytes, err := json.Marshal(model)
+    if err != nil {
+        t.Fatal(err)
+    }
+    path := filepath.Join(dir, "roundtrip.json")
+    if err := ioutil.WriteFile(path, bytes, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if r := selfPlay(6, 4, path); r.Status != SimulationComplete {
+        t.Errorf("model play: %s after %d moves %s", r.Status, len(r.Moves), r.Panic)
+    }
+}
@@ -8,5 +8,6 @@ const (
     Abandoned
     Scoring
     Complete
+    ReadyCheck
 )
 
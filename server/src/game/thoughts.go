@@ -0,0 +1,50 @@
+package game
+
+import (
+    "time"
+    "local/hansa/client"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// Anyone in the game (player or observer) can ask to see why bots play what
+// they play.
+func (g *Game) handleRequestBotThoughts(c client.Client, d message.RequestBotThoughtsData) {
+    g.debugf("(%s) Bot thoughts: %t", c.Identity(), d.Enabled)
+    if d.Enabled {
+        g.thoughtWatchers[c.Identity()] = true
+    } else {
+        delete(g.thoughtWatchers, c.Identity())
+    }
+}
+
+func (g *Game) handleBotThoughts(p int, c client.Client, d message.BotThoughtsData) {
+    t := c.Identity().Type
+    if t != simple.IdentityTypeBot && t != simple.IdentityTypeExternalBot {
+        g.clientError(c, "Client Error", "Only bots have thoughts")
+        return
+    }
+    if len(g.thoughtWatchers) == 0 {
+        return
+    }
+
+    m := message.Server{
+        SType: message.NotifyBotThoughts,
+        Time: time.Now(),
+        Data: message.NotifyBotThoughtsData{
+            Identity: c.Identity(),
+            Player: p,
+            Thoughts: d,
+        },
+    }
+    for _, p := range g.players {
+        if g.thoughtWatchers[p.Client.Identity()] {
+            p.Client.Send(m)
+        }
+    }
+    for i, o := range g.observers {
+        if g.thoughtWatchers[i] {
+            o.Send(m)
+        }
+    }
+}
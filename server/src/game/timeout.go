@@ -4,4 +4,6 @@ type TimeoutType int
 const (
     NoneTimeoutType TimeoutType = iota
     AbandonedTimeoutType
+    RematchTimeoutType
+    ReadyCheckTimeoutType
 )
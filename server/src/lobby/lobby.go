@@ -33,6 +33,8 @@ type Lobby struct {
     gamejoin chan GameJoin
     broadcast chan message.Broadcast
     cleanupGames chan int
+    rematch chan game.Rematch
+    mm *Matchmaker
 
     // The primary thing we are a lobby for.
     games []*game.Game
@@ -55,8 +57,10 @@ func New(config simple.Config, uh *user.Handler, db *database.DB, bm *bot.Manage
         gamejoin: make(chan GameJoin, 10),
         broadcast: make(chan message.Broadcast, 10),
         cleanupGames: make(chan int),
+        rematch: make(chan game.Rematch, 10),
         games: []*game.Game{},
     }
+    r.mm = NewMatchmaker(bm, r.createGame)
     r.refreshSummary()
     return r
 }
@@ -85,6 +89,11 @@ func (l *Lobby) Broadcast(b message.Broadcast) {
     l.broadcast <-b
 }
 
+// Games call this (from their own goroutine) when their players want a rematch.
+func (l *Lobby) Rematch(r game.Rematch) {
+    l.rematch <- r
+}
+
 func (l *Lobby) handleMsg() {
     rcase := func(c reflect.Value) reflect.SelectCase {
         return reflect.SelectCase{
@@ -99,6 +108,7 @@ func (l *Lobby) handleMsg() {
     cases = append(cases, rcase(reflect.ValueOf(l.gamejoin)))
     cases = append(cases, rcase(reflect.ValueOf(l.broadcast)))
     cases = append(cases, rcase(reflect.ValueOf(l.cleanupGames)))
+    cases = append(cases, rcase(reflect.ValueOf(l.rematch)))
 
     order := []simple.Identity{}
     for i, c := range l.clients {
@@ -119,8 +129,10 @@ func (l *Lobby) handleMsg() {
         l.handleBroadcast(value.Interface().(message.Broadcast))
     case 4:
         l.handleCleanup(value.Interface().(int))
+    case 5:
+        l.handleRematch(value.Interface().(game.Rematch))
     default:
-        i := order[chosen-5]
+        i := order[chosen-6]
         if !ok {
             l.handleLeave(i)
         } else {
@@ -129,6 +141,10 @@ func (l *Lobby) handleMsg() {
             switch ty := m.CType; ty {
                 case message.CreateGame:
                     l.handleCreateGame(c, m.Data.(message.CreateGameData))
+                case message.EnqueueMatch:
+                    l.handleEnqueueMatch(c, m.Data.(message.EnqueueMatchData))
+                case message.DequeueMatch:
+                    l.handleDequeueMatch(c, m.Data.(message.DequeueMatchData))
                 default:
                     l.uh.Handle(c, m)
             }
@@ -180,9 +196,11 @@ func (l *Lobby) handleBroadcast(b message.Broadcast) {
 
 func (l *Lobby) handleLeave(i simple.Identity) {
     delete(l.clients, i)
+    l.mm.Dequeue(i)
 }
 
 func (l *Lobby) handleTick() {
+    l.mm.Match()
     l.refreshSummary()
     l.debugf("Pushing %d games to %d players", 0, len(l.clients))
     l.notify(l.summary)
@@ -200,27 +218,65 @@ func (l *Lobby) handleCleanup(id int) {
 
 func (l *Lobby) handleCreateGame(c client.Client, d message.CreateGameData) {
     l.debugf("Create Game (%s)", c.Identity())
+    if err := d.Options.Validate(); err != "" {
+        c.Send(message.NewNotifyNotification(message.NotificationError, "Create Game Error", err))
+        return
+    }
+    id := l.createGame(c.Identity(), d.Options, nil)
+    c.Send(message.Server{
+        SType: message.NotifyCreateGame,
+        Data: message.NotifyCreateGameData{
+            Id: id,
+        },
+    })
+}
+
+func (l *Lobby) handleRematch(r game.Rematch) {
+    l.debugf("Rematch (%s)", r.Creator)
+    r.Id <- l.createGame(r.Creator, r.Options, func(g *game.Game) {
+        g.Preseat(r.Seats, r.Difficulty)
+        g.SetLastRanking(r.Ranking)
+    })
+}
 
+func (l *Lobby) handleEnqueueMatch(c client.Client, d message.EnqueueMatchData) {
+    l.debugf("Enqueue match (%s): %+v", c.Identity(), d)
+    if err := l.mm.Enqueue(c, d); err != "" {
+        c.Send(message.NewNotifyNotification(message.NotificationError, "Matchmaking Error", err))
+        return
+    }
+    l.mm.Match()
+}
+
+func (l *Lobby) handleDequeueMatch(c client.Client, d message.DequeueMatchData) {
+    l.debugf("Dequeue match (%s)", c.Identity())
+    if !l.mm.Dequeue(c.Identity()) {
+        c.Send(message.NewNotifyNotification(message.NotificationError, "Matchmaking Error", "You are not in the queue"))
+    }
+}
+
+// Creates and starts a new game.  setup (if not nil) runs before the game
+// does, to carry over seats from a rematch or a match.
+func (l *Lobby) createGame(creator simple.Identity, options simple.GameOptions, setup func(*game.Game)) int {
     id, err := l.db.GetNewGameId()
     if err != nil {
         panic("Unable to GetNewGameId from lobby (dynamodb)")
     }
 
-    l.games = append([]*game.Game{game.New(id, c.Identity(), l.db, l.uh, l.bm)}, l.games...)
+    g := game.New(id, creator, options, l.db, l.uh, l.bm, l)
+    if setup != nil {
+        setup(g)
+    }
+    l.games = append([]*game.Game{g}, l.games...)
 
     initDone := make(chan struct{})
     go func() {
-        l.games[0].Run(initDone)
+        g.Run(initDone)
         l.cleanupGames <- id
     }()
     <-initDone
-    c.Send(message.Server{
-        SType: message.NotifyCreateGame,
-        Data: message.NotifyCreateGameData{
-            Id: id,
-        },
-    })
     l.refreshSummary()
+    return id
 }
 
 func (l *Lobby) refreshSummary() {
@@ -0,0 +1,165 @@
+package lobby
+
+import (
+    "math/rand"
+    "time"
+    "local/hansa/bot"
+    "local/hansa/client"
+    "local/hansa/game"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// How long a request that allows bots waits for humans before we fill the
+// rest of its game with bots.
+const botFillWait = 30 * time.Second
+
+// We don't track ratings yet, so everyone is rated this for now.
+const defaultRating = 1500
+
+type matchRequest struct {
+    c client.Client
+    prefs message.EnqueueMatchData
+    since time.Time
+}
+
+// The Matchmaker is owned by the Lobby and only used from its goroutine.  It
+// keeps a queue of players looking for a game and, whenever asked to match,
+// creates and starts games for any compatible groups it can make.
+type Matchmaker struct {
+    bm *bot.Manager
+    create func(creator simple.Identity, options simple.GameOptions, setup func(*game.Game)) int
+    queue []*matchRequest
+}
+
+func NewMatchmaker(bm *bot.Manager, create func(simple.Identity, simple.GameOptions, func(*game.Game)) int) *Matchmaker {
+    return &Matchmaker{
+        bm: bm,
+        create: create,
+        queue: []*matchRequest{},
+    }
+}
+
+// Returns an error string for the client, or "" if they were queued.  Enqueuing
+// again replaces your previous preferences.
+func (m *Matchmaker) Enqueue(c client.Client, d message.EnqueueMatchData) string {
+    if d.Players < 4 || d.Players > 5 {
+        return "Only 4-5 players is supported"
+    }
+    if d.TimeControl < 0 {
+        return "Not a valid time control"
+    }
+    if d.MinRating < 0 || d.MaxRating < 0 || (d.MaxRating > 0 && d.MinRating > d.MaxRating) {
+        return "Not a valid rating range"
+    }
+    m.Dequeue(c.Identity())
+    m.queue = append(m.queue, &matchRequest{
+        c: c,
+        prefs: d,
+        since: time.Now(),
+    })
+    return ""
+}
+
+func (m *Matchmaker) Dequeue(i simple.Identity) bool {
+    for index, r := range m.queue {
+        if r.c.Identity() == i {
+            m.queue = append(m.queue[:index], m.queue[index+1:]...)
+            return true
+        }
+    }
+    return false
+}
+
+// Oldest requests get first pick.  A group is started as soon as it is full,
+// or once everyone in it allows bots and the oldest has waited botFillWait.
+func (m *Matchmaker) Match() {
+    for i:=0;i<len(m.queue); {
+        r := m.queue[i]
+        group := []*matchRequest{r}
+        for _, q := range m.queue[i+1:] {
+            if len(group) == r.prefs.Players {
+                break
+            }
+            if compatibleWithAll(q, group) {
+                group = append(group, q)
+            }
+        }
+
+        if len(group) == r.prefs.Players || (allowBots(group) && time.Since(r.since) >= botFillWait) {
+            m.start(group)
+            continue // m.queue[i] is now the next request
+        }
+        i++
+    }
+}
+
+func (m *Matchmaker) start(group []*matchRequest) {
+    seats := []simple.Identity{}
+    for i:=0;i<5;i++ {
+        seats = append(seats, simple.EmptyIdentity)
+    }
+    for i, r := range group {
+        seats[i] = r.c.Identity()
+        m.Dequeue(r.c.Identity())
+    }
+    // Never engines or models; they're only for games their owner sets up.
+    bots := m.bm.BuiltinIdentities()
+    rand.Shuffle(len(bots), func(i, j int) { bots[i], bots[j] = bots[j], bots[i] })
+    for i:=len(group);i<group[0].prefs.Players;i++ {
+        seats[i] = bots[0]
+        bots = bots[1:]
+    }
+
+    options := simple.GameOptions{
+        TimeControl: group[0].prefs.TimeControl,
+    }
+    id := m.create(group[0].c.Identity(), options, func(g *game.Game) {
+        g.Preseat(seats, nil)
+        g.Autostart()
+    })
+    for _, r := range group {
+        r.c.Send(message.Server{
+            SType: message.NotifyMatchFound,
+            Time: time.Now(),
+            Data: message.NotifyMatchFoundData{
+                Id: id,
+            },
+        })
+    }
+}
+
+func compatibleWithAll(q *matchRequest, group []*matchRequest) bool {
+    for _, r := range group {
+        if q.prefs.Players != r.prefs.Players || q.prefs.TimeControl != r.prefs.TimeControl {
+            return false
+        }
+        if !inRange(rating(q.c.Identity()), r.prefs) || !inRange(rating(r.c.Identity()), q.prefs) {
+            return false
+        }
+    }
+    return true
+}
+
+func allowBots(group []*matchRequest) bool {
+    for _, r := range group {
+        if !r.prefs.AllowBots {
+            return false
+        }
+    }
+    return true
+}
+
+func inRange(rating int, d message.EnqueueMatchData) bool {
+    if d.MinRating > 0 && rating < d.MinRating {
+        return false
+    }
+    if d.MaxRating > 0 && rating > d.MaxRating {
+        return false
+    }
+    return true
+}
+
+func rating(i simple.Identity) int {
+    return defaultRating
+}
@@ -0,0 +1,5 @@
+package message
+
+type AbandonVoteData struct {
+    Abandon bool
+}
@@ -0,0 +1,5 @@
+package message
+
+type AnswerRematchData struct {
+    Accept bool
+}
@@ -0,0 +1,23 @@
+package message
+
+// Why a bot is about to do what it does, sent by bots right before the
+// subactions it explains.  The game passes it on (as NotifyBotThoughts) to
+// anyone who asked with RequestBotThoughts.
+type BotThoughtsData struct {
+    Summary string
+    Plans []PlanThought // best first; Plans[0] is the one being played
+}
+
+type PlanThought struct {
+    Goal string
+    RouteId int
+    Fitness float64
+    Calculation string
+}
+
+func (d BotThoughtsData) Clone() BotThoughtsData {
+    if d.Plans != nil {
+        d.Plans = append(make([]PlanThought, 0, len(d.Plans)), d.Plans...)
+    }
+    return d
+}
@@ -21,6 +21,20 @@ const (
     DoSubaction
     EndTurn
     EndBump
+    RequestRematch
+    AnswerRematch
+    Resign
+    AbandonVote
+    UpdateGameOptions
+    RequestColor
+    KickSeat
+    LockSeat
+    Ready
+    EnqueueMatch
+    DequeueMatch
+    BotThoughts
+    RequestBotThoughts
+    RequestFullGame
 )
 var CTypeNames = map[CType]string {
     CTypeNone: "CTypeNone",
@@ -35,6 +49,20 @@ var CTypeNames = map[CType]string {
     DoSubaction: "DoSubaction",
     EndTurn: "EndTurn",
     EndBump: "EndBump",
+    RequestRematch: "RequestRematch",
+    AnswerRematch: "AnswerRematch",
+    Resign: "Resign",
+    AbandonVote: "AbandonVote",
+    UpdateGameOptions: "UpdateGameOptions",
+    RequestColor: "RequestColor",
+    KickSeat: "KickSeat",
+    LockSeat: "LockSeat",
+    Ready: "Ready",
+    EnqueueMatch: "EnqueueMatch",
+    DequeueMatch: "DequeueMatch",
+    BotThoughts: "BotThoughts",
+    RequestBotThoughts: "RequestBotThoughts",
+    RequestFullGame: "RequestFullGame",
 }
 func (t CType) String() string {
     return fmt.Sprintf("%s", CTypeNames[t])
@@ -97,6 +125,62 @@ func UnmarshalClient(bytes []byte) (Client, error) {
             var d EndBumpData
             err = json.Unmarshal(moreBytes, &d)
             c.Data = d
+        case RequestRematch:
+            var d RequestRematchData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case AnswerRematch:
+            var d AnswerRematchData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case Resign:
+            var d ResignData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case AbandonVote:
+            var d AbandonVoteData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case UpdateGameOptions:
+            var d UpdateGameOptionsData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case RequestColor:
+            var d RequestColorData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case KickSeat:
+            var d KickSeatData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case LockSeat:
+            var d LockSeatData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case Ready:
+            var d ReadyData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case EnqueueMatch:
+            var d EnqueueMatchData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case DequeueMatch:
+            var d DequeueMatchData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case BotThoughts:
+            var d BotThoughtsData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case RequestBotThoughts:
+            var d RequestBotThoughtsData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
+        case RequestFullGame:
+            var d RequestFullGameData
+            err = json.Unmarshal(moreBytes, &d)
+            c.Data = d
         default:
             return Client{}, errors.New(fmt.Sprintf("Unknown CType: %d", c.CType))
     }
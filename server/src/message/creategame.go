@@ -1,3 +1,9 @@
 package message
 
-type CreateGameData struct {}
+import (
+    "local/hansa/simple"
+)
+
+type CreateGameData struct {
+    Options simple.GameOptions
+}
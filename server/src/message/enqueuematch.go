@@ -0,0 +1,10 @@
+package message
+
+// A MinRating/MaxRating of 0 leaves that side of the range open.
+type EnqueueMatchData struct {
+    Players int
+    TimeControl int
+    AllowBots bool
+    MinRating int
+    MaxRating int
+}
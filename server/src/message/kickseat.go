@@ -0,0 +1,5 @@
+package message
+
+type KickSeatData struct {
+    Index int
+}
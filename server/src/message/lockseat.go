@@ -0,0 +1,6 @@
+package message
+
+type LockSeatData struct {
+    Index int
+    Lock bool
+}
@@ -0,0 +1,14 @@
+package message
+
+import (
+    "local/hansa/simple"
+)
+
+type NotifyAbandonedData struct {
+    Scores []map[simple.ScoreType]int
+}
+
+func (d NotifyAbandonedData) Clone() NotifyAbandonedData {
+    d.Scores = simple.CloneScores(d.Scores)
+    return d
+}
@@ -0,0 +1,12 @@
+package message
+
+import (
+    "local/hansa/simple"
+)
+
+type NotifyAbandonVoteData struct {
+    Identity simple.Identity
+    Abandon bool
+    Votes int
+    Needed int
+}
@@ -0,0 +1,16 @@
+package message
+
+import (
+    "local/hansa/simple"
+)
+
+type NotifyBotThoughtsData struct {
+    Identity simple.Identity
+    Player int
+    Thoughts BotThoughtsData
+}
+
+func (d NotifyBotThoughtsData) Clone() NotifyBotThoughtsData {
+    d.Thoughts = d.Thoughts.Clone()
+    return d
+}
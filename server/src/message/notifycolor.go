@@ -0,0 +1,15 @@
+package message
+
+import (
+    "local/hansa/simple"
+)
+
+// The seat at Index took Color, and the (empty) seat at OtherIndex got its
+// old color.
+type NotifyColorData struct {
+    Identity simple.Identity
+    Index int
+    Color simple.PlayerColor
+    OtherIndex int
+    OtherColor simple.PlayerColor
+}
@@ -7,3 +7,8 @@ import (
 type NotifyCompleteData struct {
     Scores []map[simple.ScoreType]int
 }
+
+func (d NotifyCompleteData) Clone() NotifyCompleteData {
+    d.Scores = simple.CloneScores(d.Scores)
+    return d
+}
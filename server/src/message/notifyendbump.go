@@ -8,3 +8,8 @@ type NotifyEndBumpData struct {
     TurnState simple.TurnState
     Elapsed []int64
 }
+
+func (d NotifyEndBumpData) Clone() NotifyEndBumpData {
+    d.Elapsed = cloneElapsed(d.Elapsed)
+    return d
+}
@@ -7,9 +7,18 @@ import (
 type NotifyFullGameData struct {
     Status int
     Creator simple.Identity
+    Options simple.GameOptions
     Table simple.Table
     TurnState simple.TurnState
     Scores []int
     FinalScores []map[simple.ScoreType]int
     Elapsed []int64
 }
+
+func (d NotifyFullGameData) Clone() NotifyFullGameData {
+    d.Table = d.Table.Clone()
+    d.Scores = simple.CloneInts(d.Scores)
+    d.FinalScores = simple.CloneScores(d.FinalScores)
+    d.Elapsed = cloneElapsed(d.Elapsed)
+    return d
+}
@@ -0,0 +1,9 @@
+package message
+
+import (
+    "local/hansa/simple"
+)
+
+type NotifyGameOptionsData struct {
+    Options simple.GameOptions
+}
@@ -33,4 +33,29 @@ type GameSummary struct {
     Colors []simple.PlayerColor
     Scores []int
     Observers int
+
+    // By player, the bot weights (Name@Version) they play; "" for humans and
+    // bots that play none.
+    BotWeights []string
+}
+
+func (d NotifyLobbyData) Clone() NotifyLobbyData {
+    if d.Games != nil {
+        games := make([]GameSummary, len(d.Games))
+        for i, g := range d.Games {
+            if g.Players != nil {
+                g.Players = append(make([]simple.Identity, 0, len(g.Players)), g.Players...)
+            }
+            if g.Colors != nil {
+                g.Colors = append(make([]simple.PlayerColor, 0, len(g.Colors)), g.Colors...)
+            }
+            g.Scores = simple.CloneInts(g.Scores)
+            if g.BotWeights != nil {
+                g.BotWeights = append(make([]string, 0, len(g.BotWeights)), g.BotWeights...)
+            }
+            games[i] = g
+        }
+        d.Games = games
+    }
+    return d
 }
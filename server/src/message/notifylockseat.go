@@ -0,0 +1,6 @@
+package message
+
+type NotifyLockSeatData struct {
+    Index int
+    Lock bool
+}
@@ -0,0 +1,5 @@
+package message
+
+type NotifyMatchFoundData struct {
+    Id int
+}
@@ -8,3 +8,8 @@ type NotifyNextTurnData struct {
     TurnState simple.TurnState
     Elapsed []int64
 }
+
+func (d NotifyNextTurnData) Clone() NotifyNextTurnData {
+    d.Elapsed = cloneElapsed(d.Elapsed)
+    return d
+}
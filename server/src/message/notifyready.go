@@ -0,0 +1,10 @@
+package message
+
+import (
+    "local/hansa/simple"
+)
+
+type NotifyReadyData struct {
+    Identity simple.Identity
+    Index int
+}
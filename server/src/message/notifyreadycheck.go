@@ -0,0 +1,12 @@
+package message
+
+import (
+    "time"
+)
+
+// Sent when the ready check begins (Begin) and when it fails for lack of
+// players.
+type NotifyReadyCheckData struct {
+    Begin bool
+    Deadline time.Time
+}
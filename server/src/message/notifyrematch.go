@@ -0,0 +1,5 @@
+package message
+
+type NotifyRematchData struct {
+    Id int
+}
@@ -0,0 +1,11 @@
+package message
+
+import (
+    "time"
+    "local/hansa/simple"
+)
+
+type NotifyRematchRequestData struct {
+    Requester simple.Identity
+    Deadline time.Time
+}
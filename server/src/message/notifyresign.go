@@ -0,0 +1,12 @@
+package message
+
+import (
+    "local/hansa/simple"
+)
+
+// Replacement is the EmptyIdentity when the player was frozen instead.
+type NotifyResignData struct {
+    Player int
+    Identity simple.Identity
+    Replacement simple.Identity
+}
@@ -8,5 +8,6 @@ type NotifySitdownData struct {
     Identity simple.Identity
     Index int
     Sitdown bool
+    Difficulty simple.Difficulty // Bots only
 }
 
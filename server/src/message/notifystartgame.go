@@ -6,4 +6,14 @@ import (
 
 type NotifyStartGameData struct {
     Table simple.Table
+    TurnOrder simple.TurnOrder
+    Order []simple.Identity
+}
+
+func (d NotifyStartGameData) Clone() NotifyStartGameData {
+    d.Table = d.Table.Clone()
+    if d.Order != nil {
+        d.Order = append(make([]simple.Identity, 0, len(d.Order)), d.Order...)
+    }
+    return d
 }
@@ -17,7 +17,13 @@ type NotifySubactionData struct {
 
     // This means that the game is ending after this Action completes.
     Gameend bool
-}
-
 
+    // The server's Table.Hash after this Subaction.  If ours differs after
+    // applying it, we're out of sync and should ask for a NotifyFullGame.
+    Hash simple.StateHash
+}
 
+func (d NotifySubactionData) Clone() NotifySubactionData {
+    d.Scores = simple.CloneInts(d.Scores)
+    return d
+}
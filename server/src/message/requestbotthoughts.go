@@ -0,0 +1,5 @@
+package message
+
+type RequestBotThoughtsData struct {
+    Enabled bool
+}
@@ -0,0 +1,3 @@
+package message
+
+type RequestRematchData struct {}
@@ -1,8 +1,15 @@
 package message
 
+import (
+    "local/hansa/simple"
+)
+
 type RequestSitdownBotData struct {
     Id string
     Index int
     Sitdown bool
+
+    // Only for server bots (not X ids); NoneDifficulty is the bot's default.
+    Difficulty simple.Difficulty
 }
 
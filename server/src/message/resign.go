@@ -0,0 +1,7 @@
+package message
+
+// An empty BotId freezes the resigning player instead of handing the seat to
+// a bot.
+type ResignData struct {
+    BotId string
+}
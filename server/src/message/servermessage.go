@@ -33,6 +33,18 @@ const (
     NotifyScoringBegin
     NotifyEndgameScoring
     NotifyComplete
+    NotifyRematchRequest
+    NotifyRematch
+    NotifyResign
+    NotifyAbandonVote
+    NotifyAbandoned
+    NotifyGameOptions
+    NotifyColor
+    NotifyLockSeat
+    NotifyReadyCheck
+    NotifyReady
+    NotifyMatchFound
+    NotifyBotThoughts
 )
 var STypeNames = map[SType]string {
     STypeNone: "STypeNone",
@@ -58,6 +70,18 @@ var STypeNames = map[SType]string {
     NotifyScoringBegin: "NotifyScoringBegin",
     NotifyEndgameScoring: "NotifyEndgameScoring",
     NotifyComplete: "NotifyComplete",
+    NotifyRematchRequest: "NotifyRematchRequest",
+    NotifyRematch: "NotifyRematch",
+    NotifyResign: "NotifyResign",
+    NotifyAbandonVote: "NotifyAbandonVote",
+    NotifyAbandoned: "NotifyAbandoned",
+    NotifyGameOptions: "NotifyGameOptions",
+    NotifyColor: "NotifyColor",
+    NotifyLockSeat: "NotifyLockSeat",
+    NotifyReadyCheck: "NotifyReadyCheck",
+    NotifyReady: "NotifyReady",
+    NotifyMatchFound: "NotifyMatchFound",
+    NotifyBotThoughts: "NotifyBotThoughts",
 }
 
 func (t SType) String() string {
@@ -70,6 +94,40 @@ type Server struct {
     Data interface{}
 }
 
+// A deep copy, much cheaper than a round trip through UnmarshalServer.  Data
+// types holding slices or maps clone themselves; the rest are plain values
+// and copy as they are.
+func (s Server) Clone() Server {
+    switch d := s.Data.(type) {
+        case NotifyLobbyData:
+            s.Data = d.Clone()
+        case NotifyFullGameData:
+            s.Data = d.Clone()
+        case NotifyStartGameData:
+            s.Data = d.Clone()
+        case NotifySubactionData:
+            s.Data = d.Clone()
+        case NotifyNextTurnData:
+            s.Data = d.Clone()
+        case NotifyEndBumpData:
+            s.Data = d.Clone()
+        case NotifyCompleteData:
+            s.Data = d.Clone()
+        case NotifyAbandonedData:
+            s.Data = d.Clone()
+        case NotifyBotThoughtsData:
+            s.Data = d.Clone()
+    }
+    return s
+}
+
+func cloneElapsed(e []int64) []int64 {
+    if e == nil {
+        return nil
+    }
+    return append(make([]int64, 0, len(e)), e...)
+}
+
 func UnmarshalServer(bytes []byte) (Server, error) {
     var s Server
     err := json.Unmarshal(bytes, &s)
@@ -171,6 +229,54 @@ func UnmarshalServer(bytes []byte) (Server, error) {
             var d NotifyCompleteData
             err = json.Unmarshal(moreBytes, &d)
             s.Data = d
+        case NotifyRematchRequest:
+            var d NotifyRematchRequestData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyRematch:
+            var d NotifyRematchData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyResign:
+            var d NotifyResignData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyAbandonVote:
+            var d NotifyAbandonVoteData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyAbandoned:
+            var d NotifyAbandonedData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyGameOptions:
+            var d NotifyGameOptionsData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyColor:
+            var d NotifyColorData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyLockSeat:
+            var d NotifyLockSeatData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyReadyCheck:
+            var d NotifyReadyCheckData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyReady:
+            var d NotifyReadyData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyMatchFound:
+            var d NotifyMatchFoundData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
+        case NotifyBotThoughts:
+            var d NotifyBotThoughtsData
+            err = json.Unmarshal(moreBytes, &d)
+            s.Data = d
         default:
             return Server{}, errors.New(fmt.Sprintf("Unknown SType: %d", s.SType))
     }
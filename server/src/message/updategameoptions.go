@@ -0,0 +1,9 @@
+package message
+
+import (
+    "local/hansa/simple"
+)
+
+type UpdateGameOptionsData struct {
+    Options simple.GameOptions
+}
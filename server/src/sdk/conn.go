@@ -0,0 +1,199 @@
+package sdk
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    stdlog "log"
+    "net/http"
+    "net/url"
+    "sync"
+    "time"
+    "github.com/gorilla/websocket"
+    "local/hansa/message"
+)
+
+type Config struct {
+    // We connect to wss://Host/ws/g/GameId (ws:// if Insecure, for a local
+    // server).
+    Host string
+    GameId int
+    Insecure bool
+
+    // Either a bot login (an X id and its key, see database/botlogin.go) or
+    // the value of a HansaAuthN cookie, as a browser would send it.
+    BotId string
+    BotKey string
+    Cookie string
+
+    // Reconnects back off from MinBackoff (default 1s) doubling to MaxBackoff
+    // (default 30s).  MaxReconnects 0 means keep trying forever.
+    MinBackoff time.Duration
+    MaxBackoff time.Duration
+    MaxReconnects int
+
+    // Defaults to the standard library's log.Printf.
+    Logf func(format string, args ...interface{})
+}
+
+// A Conn plays (or watches) one game.  The server sends a NotifyFullGame
+// every time we connect, so the Game mirror is rebuilt after a reconnect and
+// the Handler can pick up where it left off.
+type Conn struct {
+    config Config
+    game *Game
+
+    lock sync.Mutex
+    ws *websocket.Conn
+    closed bool
+}
+
+var ErrForbidden = errors.New("sdk: the server rejected our credentials")
+
+func New(config Config) *Conn {
+    if config.MinBackoff == 0 {
+        config.MinBackoff = time.Second
+    }
+    if config.MaxBackoff == 0 {
+        config.MaxBackoff = 30 * time.Second
+    }
+    if config.Logf == nil {
+        config.Logf = stdlog.Printf
+    }
+    return &Conn{
+        config: config,
+        game: &Game{
+            Id: config.GameId,
+            Player: -1,
+        },
+    }
+}
+
+// Only use this from Handler callbacks (or after Run returns); the Conn
+// changes it on its own goroutine.
+func (c *Conn) Game() *Game {
+    return c.game
+}
+
+// Blocks, calling h for every message, until Close, bad credentials, or
+// MaxReconnects failed attempts in a row.
+func (c *Conn) Run(h Handler) error {
+    backoff := c.config.MinBackoff
+    failures := 0
+    for {
+        err := c.connect()
+        if err == ErrForbidden {
+            return err
+        }
+        if err == nil {
+            backoff = c.config.MinBackoff
+            failures = 0
+            err = c.serve(h)
+        }
+        if c.isClosed() {
+            return nil
+        }
+
+        failures++
+        if c.config.MaxReconnects > 0 && failures > c.config.MaxReconnects {
+            return fmt.Errorf("sdk: giving up after %d reconnects: %s", c.config.MaxReconnects, err)
+        }
+        c.config.Logf("sdk: (G%d) disconnected (%s), reconnecting in %s", c.config.GameId, err, backoff)
+        time.Sleep(backoff)
+        backoff *= 2
+        if backoff > c.config.MaxBackoff {
+            backoff = c.config.MaxBackoff
+        }
+    }
+}
+
+// Safe to call from any goroutine.  Handlers should usually return their
+// responses instead.
+func (c *Conn) Send(m message.Client) error {
+    bytes, err := json.Marshal(m)
+    if err != nil {
+        return err
+    }
+    c.lock.Lock()
+    defer c.lock.Unlock()
+    if c.ws == nil {
+        return errors.New("sdk: not connected")
+    }
+    return c.ws.WriteMessage(websocket.TextMessage, bytes)
+}
+
+func (c *Conn) Close() {
+    c.lock.Lock()
+    defer c.lock.Unlock()
+    c.closed = true
+    if c.ws != nil {
+        c.ws.Close()
+    }
+}
+
+func (c *Conn) isClosed() bool {
+    c.lock.Lock()
+    defer c.lock.Unlock()
+    return c.closed
+}
+
+func (c *Conn) connect() error {
+    scheme := "wss"
+    if c.config.Insecure {
+        scheme = "ws"
+    }
+    u := url.URL{
+        Scheme: scheme,
+        Host: c.config.Host,
+        Path: fmt.Sprintf("/ws/g/%d", c.config.GameId),
+    }
+    header := http.Header{}
+    if c.config.BotId != "" {
+        header.Set("Authorization", fmt.Sprintf("Bot %s:%s", c.config.BotId, c.config.BotKey))
+    } else if c.config.Cookie != "" {
+        header.Set("Cookie", "HansaAuthN=" + c.config.Cookie)
+    }
+
+    ws, resp, err := websocket.DefaultDialer.Dial(u.String(), header)
+    if err != nil {
+        if resp != nil && resp.StatusCode == http.StatusForbidden {
+            return ErrForbidden
+        }
+        return err
+    }
+
+    c.lock.Lock()
+    defer c.lock.Unlock()
+    if c.closed {
+        ws.Close()
+        return errors.New("sdk: closed")
+    }
+    c.ws = ws
+    return nil
+}
+
+func (c *Conn) serve(h Handler) error {
+    defer func() {
+        c.lock.Lock()
+        c.ws.Close()
+        c.ws = nil
+        c.lock.Unlock()
+    }()
+    for {
+        _, bytes, err := c.ws.ReadMessage()
+        if err != nil {
+            return err
+        }
+        m, err := message.UnmarshalServer(bytes)
+        if err != nil {
+            // Probably a newer server than this package; skip it.
+            c.config.Logf("sdk: (G%d) unable to unmarshal message: %s", c.config.GameId, err)
+            continue
+        }
+        for _, r := range dispatch(h, c.game, m) {
+            if err = c.Send(r); err != nil {
+                return err
+            }
+        }
+    }
+}
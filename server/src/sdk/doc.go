@@ -0,0 +1,41 @@
+// Package sdk is for writing external bots and tools against a running Hansa
+// server.  It handles the websocket, authentication and reconnection, decodes
+// every message into its typed message.XData, and keeps a local simple.Table
+// mirror up to date, so a bot only has to decide what to do:
+//
+//     conn := sdk.New(sdk.Config{
+//         Host: "chansas.com",
+//         GameId: 12,
+//         BotId: "X3",
+//         BotKey: "...",
+//     })
+//     err := conn.Run(&myBot{})
+//
+// where myBot embeds sdk.Base and overrides the callbacks it cares about.
+// Every callback gets the Game mirror (already updated with the message) and
+// returns the message.Client responses to send, in order.
+//
+// Things the server expects that aren't obvious from the message package:
+//
+// * simple.Location.Subindex means different things per LocationType.  For
+//   cities it is 0 for a normal office, 1 for the virtual office, and 2 for
+//   Coellen.  For routes it is 0 for a piece on the spot and 1 for a piece
+//   that was just bumped off it.  For players it picks the part of the
+//   PlayerBoard (5 is Stock, 6 is Supply; see simple/location.go).
+// * Bumping: when you place on someone's piece, you first pay (TurnState
+//   BumpPaying: move BumpPayingCost pieces from your Supply to your Stock).
+//   The bumped player then gets TurnState Bumping with themselves as
+//   BumpingPlayer; they must move the piece at BumpingLocation (Subindex 1) to
+//   one of Table.ValidBumps(BumpingLocation), may place up to
+//   BumpingReplaces more pieces from their Supply, and then send EndBump.
+//   Handler.Bumped is called exactly once per bump for this.
+// * Clearing: completing a route puts you in TurnState Clearing, where you
+//   use the ClearingAward (if any) and then move each of your pieces on
+//   ClearingRouteId back to your Supply.
+// * A turn ends when you send EndTurn; it is never ended for you.
+// * Any bad Subaction gets a NotifySubactionError and changes nothing, so
+//   your mirror stays valid and you can try again.
+// * Every NotifySubaction carries the server's Table.Hash; if the mirror
+//   stops matching it, the Conn resyncs from a NotifyFullGame on its own
+//   (Game.Resyncing) and your handlers pick up from FullGame.
+package sdk
@@ -0,0 +1,115 @@
+package sdk
+
+import (
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// These are the values of message.NotifyFullGameData.Status (game.Status on
+// the server).
+const (
+    StatusNone = iota
+    StatusCreating
+    StatusRunning
+    StatusAbandoned
+    StatusScoring
+    StatusComplete
+    StatusReadyCheck
+)
+
+// The local mirror of a game.  It is only changed by the Conn, from what the
+// server sends, so it never contains a move the server hasn't accepted.
+type Game struct {
+    Id int
+    Identity simple.Identity
+    Status int
+    Options simple.GameOptions
+    Table simple.Table
+    TurnState simple.TurnState
+    Scores []int
+
+    // Our index into Table.PlayerBoards (and Scores), or -1 if we're not
+    // playing.
+    Player int
+
+    // Set when our Table stops matching the server's (see
+    // message.NotifySubactionData.Hash).  The Conn asks for a NotifyFullGame
+    // and handlers hear nothing until it comes.
+    Resyncing bool
+
+    // So Bumped is only called once per bump.
+    handledBump bool
+}
+
+func (g *Game) MyTurn() bool {
+    return g.Player != -1 &&
+        g.TurnState.Player == g.Player &&
+        g.TurnState.Type != simple.Bumping
+}
+
+func (g *Game) BeingBumped() bool {
+    return g.Player != -1 &&
+        g.TurnState.Type == simple.Bumping &&
+        g.TurnState.BumpingPlayer == g.Player
+}
+
+func (g *Game) Me() *simple.PlayerBoard {
+    if g.Player == -1 {
+        return nil
+    }
+    return &g.Table.PlayerBoards[g.Player]
+}
+
+func (g *Game) findPlayer() {
+    g.Player = -1
+    for i, pb := range g.Table.PlayerBoards {
+        if pb.Identity == g.Identity {
+            g.Player = i
+        }
+    }
+}
+
+func (g *Game) fullGame(d message.NotifyFullGameData) {
+    g.Status = d.Status
+    g.Options = d.Options
+    g.Table = d.Table
+    g.TurnState = d.TurnState
+    g.Scores = append([]int{}, d.Scores...)
+    g.handledBump = false
+    g.Resyncing = false
+    g.findPlayer()
+}
+
+func (g *Game) startGame(d message.NotifyStartGameData) {
+    g.Status = StatusRunning
+    g.Table = d.Table
+    g.TurnState = simple.NoneTurnState
+    g.Scores = make([]int, len(d.Table.PlayerBoards))
+    g.handledBump = false
+    g.findPlayer()
+}
+
+// Returns whether this subaction just bumped us.
+func (g *Game) subaction(d message.NotifySubactionData) bool {
+    for i, s := range d.Scores {
+        g.Scores[i] += s
+    }
+    g.Table.ApplySubaction(d.Subaction, g.Identity)
+    g.TurnState = d.TurnState
+    if d.Hash != 0 && g.Table.Hash() != d.Hash {
+        g.Resyncing = true
+        return false
+    }
+    if g.BeingBumped() && !g.handledBump {
+        g.handledBump = true
+        return true
+    }
+    return false
+}
+
+func (g *Game) turnState(ts simple.TurnState) {
+    g.TurnState = ts
+    if ts.Type != simple.Bumping {
+        g.handledBump = false
+    }
+}
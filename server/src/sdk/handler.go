@@ -0,0 +1,114 @@
+package sdk
+
+import (
+    "local/hansa/message"
+)
+
+// A Handler is the bot side of a Conn, like bot.Brain is inside the server.
+// Every callback runs on the Conn's goroutine after the Game mirror has been
+// updated, and returns what to send back (nil for nothing).  Embed Base to
+// only implement what you need.
+type Handler interface {
+    // The game started (or restarted for a rematch).  Turns come after this.
+    StartGame(g *Game, d message.NotifyStartGameData) []message.Client
+
+    // Sent on every (re)connect.  If it's your turn, or you are being bumped
+    // (g.BeingBumped() and !d.TurnState.BumpingMoved), you need to act here:
+    // NextTurn or Bumped won't be called for it.
+    FullGame(g *Game, d message.NotifyFullGameData) []message.Client
+
+    // Any subaction by anyone.  Bumps against us go to Bumped instead.
+    Subaction(g *Game, d message.NotifySubactionData) []message.Client
+
+    // We were just bumped; see the package doc.
+    Bumped(g *Game, d message.NotifySubactionData) []message.Client
+
+    // Any player's turn; check g.MyTurn().
+    NextTurn(g *Game, d message.NotifyNextTurnData) []message.Client
+
+    // A bump was resolved and the bumping player (maybe us) continues.
+    EndBump(g *Game, d message.NotifyEndBumpData) []message.Client
+
+    // Our last subaction was rejected; nothing changed.
+    SubactionError(g *Game, d message.NotifySubactionErrorData) []message.Client
+
+    // Everything else, for tools that care.  m.Data is already typed.
+    Other(g *Game, m message.Server) []message.Client
+}
+
+type Base struct {}
+
+func (Base) StartGame(g *Game, d message.NotifyStartGameData) []message.Client {
+    return nil
+}
+func (Base) FullGame(g *Game, d message.NotifyFullGameData) []message.Client {
+    return nil
+}
+func (Base) Subaction(g *Game, d message.NotifySubactionData) []message.Client {
+    return nil
+}
+func (Base) Bumped(g *Game, d message.NotifySubactionData) []message.Client {
+    return nil
+}
+func (Base) NextTurn(g *Game, d message.NotifyNextTurnData) []message.Client {
+    return nil
+}
+func (Base) EndBump(g *Game, d message.NotifyEndBumpData) []message.Client {
+    return nil
+}
+func (Base) SubactionError(g *Game, d message.NotifySubactionErrorData) []message.Client {
+    return nil
+}
+func (Base) Other(g *Game, m message.Server) []message.Client {
+    return nil
+}
+
+func dispatch(h Handler, g *Game, m message.Server) []message.Client {
+    if g.Resyncing && m.SType != message.NotifyFullGame {
+        return nil
+    }
+    switch m.SType {
+        case message.YourIdentity:
+            g.Identity = m.Data.(message.YourIdentityData).Identity
+            g.findPlayer()
+        case message.NotifyFullGame:
+            d := m.Data.(message.NotifyFullGameData)
+            g.fullGame(d)
+            return h.FullGame(g, d)
+        case message.NotifyStartGame:
+            d := m.Data.(message.NotifyStartGameData)
+            g.startGame(d)
+            return h.StartGame(g, d)
+        case message.NotifySubaction:
+            d := m.Data.(message.NotifySubactionData)
+            if g.subaction(d) {
+                return h.Bumped(g, d)
+            }
+            if g.Resyncing {
+                return []message.Client{message.Client{
+                    CType: message.RequestFullGame,
+                    Data: message.RequestFullGameData{},
+                }}
+            }
+            return h.Subaction(g, d)
+        case message.NotifyNextTurn:
+            d := m.Data.(message.NotifyNextTurnData)
+            g.turnState(d.TurnState)
+            return h.NextTurn(g, d)
+        case message.NotifyEndBump:
+            d := m.Data.(message.NotifyEndBumpData)
+            g.turnState(d.TurnState)
+            return h.EndBump(g, d)
+        case message.NotifySubactionError:
+            return h.SubactionError(g, m.Data.(message.NotifySubactionErrorData))
+        case message.NotifyScoringBegin:
+            g.Status = StatusScoring
+        case message.NotifyComplete:
+            g.Status = StatusComplete
+        case message.NotifyAbandoned:
+            g.Status = StatusAbandoned
+        case message.NotifyGameOptions:
+            g.Options = m.Data.(message.NotifyGameOptionsData).Options
+    }
+    return h.Other(g, m)
+}
@@ -0,0 +1,285 @@
+package sdk
+
+import (
+    "fmt"
+    "reflect"
+    "testing"
+    "local/hansa/message"
+    "local/hansa/simple"
+)
+
+// Records which Handler methods dispatch called, and answers each with a
+// message naming it, so tests can see what would be sent.
+type recorder struct {
+    calls []string
+}
+
+func (r *recorder) reply(call string) []message.Client {
+    r.calls = append(r.calls, call)
+    return []message.Client{message.Client{
+        CType: message.DoSubaction,
+        Data: call,
+    }}
+}
+
+func (r *recorder) StartGame(g *Game, d message.NotifyStartGameData) []message.Client {
+    return r.reply("StartGame")
+}
+func (r *recorder) FullGame(g *Game, d message.NotifyFullGameData) []message.Client {
+    return r.reply("FullGame")
+}
+func (r *recorder) Subaction(g *Game, d message.NotifySubactionData) []message.Client {
+    return r.reply("Subaction")
+}
+func (r *recorder) Bumped(g *Game, d message.NotifySubactionData) []message.Client {
+    return r.reply("Bumped")
+}
+func (r *recorder) NextTurn(g *Game, d message.NotifyNextTurnData) []message.Client {
+    return r.reply("NextTurn")
+}
+func (r *recorder) EndBump(g *Game, d message.NotifyEndBumpData) []message.Client {
+    return r.reply("EndBump")
+}
+func (r *recorder) SubactionError(g *Game, d message.NotifySubactionErrorData) []message.Client {
+    return r.reply("SubactionError")
+}
+func (r *recorder) Other(g *Game, m message.Server) []message.Client {
+    return r.reply(fmt.Sprintf("Other %s", m.SType))
+}
+
+// Whether sent is exactly what the handler answered to call.
+func answered(sent []message.Client, call string) bool {
+    return len(sent) == 1 && sent[0].Data == call
+}
+
+// A 4 player table just dealt, with player 1 (us) already on route 0.
+func handlerTestTable() simple.Table {
+    t := simple.Table{
+        Board: simple.NewBase45Board(),
+        PlayerBoards: simple.NewBasePlayerBoards(),
+        Tokens: []simple.Token{},
+    }
+    t.PlayerBoards = t.PlayerBoards[:4]
+    for i, _ := range t.PlayerBoards {
+        t.PlayerBoards[i].Identity = simple.NewBotIdentity(fmt.Sprintf("X%d", i), fmt.Sprintf("Player %d", i))
+    }
+    t.DealStartPieces()
+    t.Board.Routes[0].Spots[0], t.PlayerBoards[1].Supply[1] = t.PlayerBoards[1].Supply[1], simple.Piece{}
+    return t
+}
+
+// Player 0 places a cube on an empty spot of route 1.
+func placeSubaction(t *simple.Table) simple.Subaction {
+    return simple.Subaction{
+        Source: simple.Location{Type: simple.PlayerLocationType, Id: 0, Index: 6, Subindex: 2},
+        Dest: simple.Location{Type: simple.RouteLocationType, Id: 1, Index: 0},
+        Piece: t.PlayerBoards[0].Supply[2],
+    }
+}
+
+// Player 0 bumps our cube off route 0.
+func bumpSubaction(t *simple.Table) simple.Subaction {
+    return simple.Subaction{
+        Source: simple.Location{Type: simple.PlayerLocationType, Id: 0, Index: 6, Subindex: 1},
+        Dest: simple.Location{Type: simple.RouteLocationType, Id: 0, Index: 0},
+        Piece: t.PlayerBoards[0].Supply[1],
+    }
+}
+
+func bumpingUs() simple.TurnState {
+    ts := simple.NoneTurnState
+    ts.Type = simple.Bumping
+    ts.Player = 0
+    ts.BumpingPlayer = 1
+    ts.BumpingLocation = simple.Location{Type: simple.RouteLocationType, Id: 0, Index: 0, Subindex: 1}
+    return ts
+}
+
+// A Game as a Conn would have it: told who we are, and the game started.
+func startedGame(t *testing.T, h Handler) (*Game, simple.Table) {
+    table := handlerTestTable()
+    g := &Game{Player: -1}
+    dispatch(h, g, message.Server{
+        SType: message.YourIdentity,
+        Data: message.YourIdentityData{Identity: table.PlayerBoards[1].Identity},
+    })
+    sent := dispatch(h, g, message.Server{
+        SType: message.NotifyStartGame,
+        Data: message.NotifyStartGameData{Table: table.Clone()},
+    })
+    if !answered(sent, "StartGame") {
+        t.Fatalf("StartGame sent %v", sent)
+    }
+    return g, table
+}
+
+func TestDispatchMirrorsGame(t *testing.T) {
+    h := &recorder{}
+    g, table := startedGame(t, h)
+    if g.Player != 1 || g.Status != StatusRunning || len(g.Scores) != 4 {
+        t.Fatalf("after starting: Player %d, Status %d, Scores %v", g.Player, g.Status, g.Scores)
+    }
+
+    s := placeSubaction(&table)
+    table.ApplySubaction(s, simple.EmptyIdentity)
+    ts := simple.NoneTurnState
+    ts.Player = 0
+    ts.ActionsLeft = 1
+    sent := dispatch(h, g, message.Server{
+        SType: message.NotifySubaction,
+        Data: message.NotifySubactionData{
+            Subaction: s,
+            Scores: []int{0, 2, 0, 0},
+            TurnState: ts,
+            Hash: table.Hash(),
+        },
+    })
+    if !answered(sent, "Subaction") {
+        t.Errorf("a subaction sent %v", sent)
+    }
+    if !g.Table.Equals(&table) {
+        t.Errorf("our table doesn't match after %v", s)
+    }
+    if !reflect.DeepEqual(g.Scores, []int{0, 2, 0, 0}) || g.TurnState != ts || g.Resyncing {
+        t.Errorf("after a subaction: Scores %v, TurnState %v, Resyncing %t", g.Scores, g.TurnState, g.Resyncing)
+    }
+
+    ts = simple.NoneTurnState
+    ts.Player = 1
+    ts.ActionsLeft = 2
+    sent = dispatch(h, g, message.Server{
+        SType: message.NotifyNextTurn,
+        Data: message.NotifyNextTurnData{TurnState: ts},
+    })
+    if !answered(sent, "NextTurn") || !g.MyTurn() {
+        t.Errorf("our turn sent %v, MyTurn %t", sent, g.MyTurn())
+    }
+
+    options := simple.GameOptions{TimeControl: 3}
+    dispatch(h, g, message.Server{
+        SType: message.NotifyGameOptions,
+        Data: message.NotifyGameOptionsData{Options: options},
+    })
+    sent = dispatch(h, g, message.Server{SType: message.NotifyComplete})
+    if g.Options != options || g.Status != StatusComplete || !answered(sent, "Other NotifyComplete") {
+        t.Errorf("Options %v, Status %d, sent %v", g.Options, g.Status, sent)
+    }
+}
+
+// Bumped is called once per bump, not again for the subactions that follow
+// until the bump ends.
+func TestDispatchHandledBump(t *testing.T) {
+    h := &recorder{}
+    g, table := startedGame(t, h)
+
+    bump := func() []message.Client {
+        s := bumpSubaction(&table)
+        table.ApplySubaction(s, simple.EmptyIdentity)
+        return dispatch(h, g, message.Server{
+            SType: message.NotifySubaction,
+            Data: message.NotifySubactionData{
+                Subaction: s,
+                Scores: []int{0, 0, 0, 0},
+                TurnState: bumpingUs(),
+                Hash: table.Hash(),
+            },
+        })
+    }
+    sent := bump()
+    if !answered(sent, "Bumped") || !g.BeingBumped() {
+        t.Fatalf("being bumped sent %v, BeingBumped %t", sent, g.BeingBumped())
+    }
+
+    // We move our bumped piece.
+    s := simple.Subaction{
+        Source: bumpingUs().BumpingLocation,
+        Dest: simple.Location{Type: simple.RouteLocationType, Id: 1, Index: 0},
+        Piece: table.GetPiece(bumpingUs().BumpingLocation),
+    }
+    table.ApplySubaction(s, simple.EmptyIdentity)
+    ts := bumpingUs()
+    ts.BumpingMoved = true
+    sent = dispatch(h, g, message.Server{
+        SType: message.NotifySubaction,
+        Data: message.NotifySubactionData{Subaction: s, Scores: []int{0, 0, 0, 0}, TurnState: ts, Hash: table.Hash()},
+    })
+    if !answered(sent, "Subaction") {
+        t.Errorf("our own move during the bump sent %v", sent)
+    }
+
+    ts = simple.NoneTurnState
+    ts.Player = 0
+    sent = dispatch(h, g, message.Server{
+        SType: message.NotifyEndBump,
+        Data: message.NotifyEndBumpData{TurnState: ts},
+    })
+    if !answered(sent, "EndBump") || g.BeingBumped() {
+        t.Errorf("the bump ending sent %v, BeingBumped %t", sent, g.BeingBumped())
+    }
+
+    // Put our cube back on route 0 to be bumped again.
+    table.ApplySubaction(simple.Subaction{
+        Source: simple.Location{Type: simple.RouteLocationType, Id: 0, Index: 0},
+        Dest: simple.Location{Type: simple.PlayerLocationType, Id: 0, Index: 5, Subindex: 10},
+        Piece: table.Board.Routes[0].Spots[0],
+    }, simple.EmptyIdentity)
+    table.ApplySubaction(simple.Subaction{
+        Source: simple.Location{Type: simple.RouteLocationType, Id: 1, Index: 0},
+        Dest: simple.Location{Type: simple.RouteLocationType, Id: 0, Index: 0},
+        Piece: table.Board.Routes[1].Spots[0],
+    }, simple.EmptyIdentity)
+    table.PlayerBoards[0].Supply[1] = table.PlayerBoards[0].Stock[10]
+    table.PlayerBoards[0].Stock[10] = simple.Piece{}
+    full := message.Server{
+        SType: message.NotifyFullGame,
+        Data: message.NotifyFullGameData{Status: StatusRunning, Table: table.Clone(), TurnState: ts, Scores: g.Scores},
+    }
+    dispatch(h, g, full)
+    if sent = bump(); !answered(sent, "Bumped") {
+        t.Errorf("the next bump sent %v", sent)
+    }
+}
+
+// A Hash that doesn't match asks for the full game, and nothing reaches the
+// handler until it comes.
+func TestDispatchResync(t *testing.T) {
+    h := &recorder{}
+    g, table := startedGame(t, h)
+
+    s := placeSubaction(&table)
+    table.ApplySubaction(s, simple.EmptyIdentity)
+    sent := dispatch(h, g, message.Server{
+        SType: message.NotifySubaction,
+        Data: message.NotifySubactionData{
+            Subaction: s,
+            Scores: []int{0, 0, 0, 0},
+            TurnState: simple.NoneTurnState,
+            Hash: table.Hash() + 1,
+        },
+    })
+    if len(sent) != 1 || sent[0].CType != message.RequestFullGame || !g.Resyncing {
+        t.Fatalf("a hash mismatch sent %v, Resyncing %t", sent, g.Resyncing)
+    }
+
+    calls := len(h.calls)
+    ts := simple.NoneTurnState
+    ts.Player = 1
+    ts.ActionsLeft = 2
+    if sent = dispatch(h, g, message.Server{SType: message.NotifyNextTurn, Data: message.NotifyNextTurnData{TurnState: ts}}); sent != nil {
+        t.Errorf("resyncing, a turn sent %v", sent)
+    }
+    if len(h.calls) != calls {
+        t.Errorf("resyncing, the handler heard %v", h.calls[calls:])
+    }
+
+    sent = dispatch(h, g, message.Server{
+        SType: message.NotifyFullGame,
+        Data: message.NotifyFullGameData{Status: StatusRunning, Table: table.Clone(), TurnState: ts, Scores: []int{1, 2, 3, 4}},
+    })
+    if !answered(sent, "FullGame") || g.Resyncing {
+        t.Errorf("the full game sent %v, Resyncing %t", sent, g.Resyncing)
+    }
+    if !g.Table.Equals(&table) || !reflect.DeepEqual(g.Scores, []int{1, 2, 3, 4}) || !g.MyTurn() {
+        t.Errorf("after resyncing: Scores %v, MyTurn %t, table matches %t", g.Scores, g.MyTurn(), g.Table.Equals(&table))
+    }
+}
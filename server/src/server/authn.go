@@ -4,6 +4,7 @@ import (
     "context"
     "fmt"
     "net/http"
+    "strings"
     "local/hansa/crypto"
     "local/hansa/log"
     "local/hansa/database"
@@ -25,6 +26,28 @@ func authNClosure(db *database.DB, config simple.Config) func(http.Handler) http
                 return
             }
 
+            // External bots use "Authorization: Bot <id>:<key>" instead of a
+            // cookie, and may only connect to games.
+            if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bot ") {
+                parts := strings.SplitN(auth[4:], ":", 2)
+                if len(parts) != 2 || !strings.HasPrefix(path, "/ws/g/") {
+                    log.Debug("Access: BadBotAuth %s (%s)", ip, path)
+                    w.WriteHeader(http.StatusForbidden)
+                    w.Write([]byte("Bot authorization is 'Bot <id>:<key>' on /ws/g/{id}"))
+                    return
+                }
+                identity, err := db.BotSignin(parts[0], []byte(parts[1]))
+                if err != nil {
+                    w.WriteHeader(http.StatusForbidden)
+                    w.Write([]byte("Bad bot id or key"))
+                    return
+                }
+                next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(),
+                    "Identity", identity,
+                )))
+                return
+            }
+
             // Ensure cookie exists, and use playerCookie over guestCookie.
             playerCookie :=  ""
             guestCookie := ""
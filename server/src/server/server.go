@@ -7,6 +7,7 @@ import (
     "runtime/debug"
     "strconv"
     "strings"
+    "time"
     "github.com/gorilla/mux"
     "github.com/gorilla/websocket"
     "local/hansa/bot"
@@ -54,6 +55,11 @@ func New(config simple.Config) *Server {
     broadcaster.uh = uh
 
     bm := bot.NewManager()
+    registerEngines(bm, config)
+    registerModels(bm, config)
+    configureMCTS(bm, config)
+    configurePlanWorkers(bm, config)
+    loadWeights(bm, config)
 
     lobby := lobby.New(config, uh, db, bm, ip, broadcaster);
     go lobby.Run(initDone)
@@ -273,6 +279,8 @@ func (s *Server) handleAdmin(w http.ResponseWriter, pe []string, r *http.Request
     switch pe[0] {
     case "hotdeploy":
         s.handleHotDeploy(w)
+    case "reloadweights":
+        s.handleReloadWeights(w)
     default:
         log.Error("URL Path has no routes: /a/%s", pe)
         s.adminError(w)
@@ -305,6 +313,16 @@ func (s *Server) handleHotDeploy(w http.ResponseWriter) {
     os.Exit(0)
 }
 
+func (s *Server) handleReloadWeights(w http.ResponseWriter) {
+    log.Info("ReloadWeights admin request recieved (/a/reloadweights)")
+    if err := s.bm.ReloadWeights(); err != nil {
+        log.Error("Unable to reload weights, keeping the old ones: %s", err)
+        s.adminError(w)
+        return
+    }
+    s.adminSuccess(w)
+}
+
 func getIP(r *http.Request) string {
 	forwarded := r.Header.Get("X-FORWARDED-FOR")
 	if forwarded != "" {
@@ -353,3 +371,104 @@ func (s *Server) cServerError(client *client.WebClient, e string, fargs ...inter
     m := message.NewInternalError("Internal Error")
     client.Send(m)
 }
+
+// Config lines like "engine-E1=Name,/path/to/engine,arg1,arg2" add local
+// engine bots.
+func registerEngines(bm *bot.Manager, config simple.Config) {
+    for k, v := range config.ConfigKeys {
+        if !strings.HasPrefix(k, "engine-") {
+            continue
+        }
+        id := strings.TrimPrefix(k, "engine-")
+        parts := strings.Split(string(v), ",")
+        if !strings.HasPrefix(id, "E") || len(parts) < 2 {
+            log.Error("Ignoring bad engine config '%s=%s'", k, v)
+            continue
+        }
+        log.Info("Registering engine %s (%s): %s", id, parts[0], parts[1])
+        bm.RegisterEngine(id, parts[0], parts[1], parts[2:]...)
+    }
+}
+
+// Config lines like "model-M1=Name,/path/to/model.json" add bots playing
+// models trained on self-play (see bot.Model).
+func registerModels(bm *bot.Manager, config simple.Config) {
+    for k, v := range config.ConfigKeys {
+        if !strings.HasPrefix(k, "model-") {
+            continue
+        }
+        id := strings.TrimPrefix(k, "model-")
+        parts := strings.Split(string(v), ",")
+        if !strings.HasPrefix(id, "M") || len(parts) != 2 {
+            log.Error("Ignoring bad model config '%s=%s'", k, v)
+            continue
+        }
+        if err := bm.RegisterModel(id, parts[0], parts[1]); err != nil {
+            log.Error("Ignoring model %s: %s", id, err)
+            continue
+        }
+        log.Info("Registered model %s (%s): %s", id, parts[0], parts[1])
+    }
+}
+
+// The optional config line "weights-dir=/path" overrides where bot weight files
+// are.  Without them bots play the built in weights.
+func loadWeights(bm *bot.Manager, config simple.Config) {
+    dir := "/home/ec2-user/hansa/server/weights"
+    if v, ok := config.ConfigKeys["weights-dir"]; ok {
+        dir = string(v)
+    }
+    if err := bm.LoadWeights(dir); err != nil {
+        log.Error("Unable to load bot weights, using the built in ones: %s", err)
+    }
+}
+
+// Optional config lines "mcts-budget=2s", "mcts-iterations=5000" and
+// "mcts-workers=4" tune Expert bots.
+func configureMCTS(bm *bot.Manager, config simple.Config) {
+    c := bot.DefaultMCTSConfig
+    if v, ok := config.ConfigKeys["mcts-budget"]; ok {
+        d, err := time.ParseDuration(string(v))
+        if err != nil {
+            log.Error("Ignoring bad mcts-budget '%s': %s", v, err)
+        } else {
+            c.Budget = d
+        }
+    }
+    if v, ok := config.ConfigKeys["mcts-iterations"]; ok {
+        n, err := strconv.Atoi(string(v))
+        if err != nil {
+            log.Error("Ignoring bad mcts-iterations '%s': %s", v, err)
+        } else {
+            c.Iterations = n
+        }
+    }
+    if v, ok := config.ConfigKeys["mcts-workers"]; ok {
+        n, err := strconv.Atoi(string(v))
+        if err != nil || n < 1 {
+            log.Error("Ignoring bad mcts-workers '%s'", v)
+        } else {
+            c.Workers = n
+        }
+    }
+    if c.Budget == 0 && c.Iterations == 0 {
+        log.Error("MCTS needs a budget or iterations, using the default budget")
+        c.Budget = bot.DefaultMCTSConfig.Budget
+    }
+    bm.ConfigureMCTS(c)
+}
+
+// The optional config line "plan-workers=4" sets how many goroutines each bot
+// generates plans on (the default is one per CPU).
+func configurePlanWorkers(bm *bot.Manager, config simple.Config) {
+    v, ok := config.ConfigKeys["plan-workers"]
+    if !ok {
+        return
+    }
+    n, err := strconv.Atoi(string(v))
+    if err != nil || n < 1 {
+        log.Error("Ignoring bad plan-workers '%s'", v)
+        return
+    }
+    bm.ConfigurePlanWorkers(n)
+}
@@ -4,22 +4,14 @@ type Board struct {
     Name string
     Cities []City
     Routes []Route
-}
 
-func (b Board) GetBonusRouteCompleted(color PlayerColor) bool {
-    start := -1
-    end := -1
+    // Our graph, once worked out (see graph); copies share it.
+    layout *boardGraph
+}
 
-    for _, c := range b.Cities {
-        if c.BonusTerminus {
-            if start == -1 {
-                start = c.Id
-            } else if end == -1 {
-                end = c.Id
-                break
-            }
-        }
-    }
+func (b *Board) GetBonusRouteCompleted(color PlayerColor) bool {
+    g := b.graph()
+    start, end := g.bonusStart, g.bonusEnd
     if end == -1 {
         return false
     }
@@ -27,32 +19,12 @@ func (b Board) GetBonusRouteCompleted(color PlayerColor) bool {
         return false
     }
 
-    adds := 1
-    oldFrontier := []City{b.Cities[start]}
-    frontier := []City{}
-    for adds > 0 {
-        adds = 0
-        for _, oc := range oldFrontier {
-            for _, nc := range b.Cities {
-                if containsCity(nc, oldFrontier) {
-                    continue
-                }
-                if nc.GetPresence(color) == 0 {
-                    continue
-                }
-                if b.adjacentCity(oc, nc) {
-                    if nc.Id == end {
-                        return true
-                    }
-                    adds++
-                    frontier = append(frontier, nc)
-                }
-            }
-        }
-        oldFrontier = append(oldFrontier, frontier...)
-        frontier = []City{}
+    presence := make([]int, len(b.Cities))
+    for i, c := range b.Cities {
+        presence[i] = c.GetPresence(color)
     }
-    return false
+    n := g.networks(presence)
+    return n.find(start) == n.find(end)
 }
 
 func (b *Board) GetFilledCityCount() int {
@@ -92,94 +64,58 @@ func (b *Board) GetNetworkScore(color PlayerColor) int {
 // This takes a function to check for presence in a city.  It's factored this
 // way so that bots can ask hypotheticals "what if I were in this city". 
 func (b *Board) getNetworkScore(getPresence func(City) int) int {
-    type Network struct {
-        Cities map[int]struct{}
-        Score int
+    presence := make([]int, len(b.Cities))
+    for i, c := range b.Cities {
+        presence[i] = getPresence(c)
     }
-    networks := []Network{}
+    n := b.graph().networks(presence)
 
-    for _, c := range b.Cities {
-        p := getPresence(c)
+    scores := make([]int, len(b.Cities))
+    max := 0
+    for c, p := range presence {
         if p == 0 {
             continue
         }
-        handled := false
-        for _, n := range networks {
-            if _, ok := n.Cities[c.Id]; ok {
-                handled = true
-                break
-            }
-        }
-        if handled {
-            continue
-        }
-        n := Network{
-            Cities: map[int]struct{}{c.Id: struct{}{}},
-            Score: p,
-        }
-
-        frontier := []int{c.Id}
-        for ;len(frontier)>0; {
-            newFrontier := []int{}
-            for _, c2 := range frontier {
-                for _, c3 := range b.Cities {
-                    if _, ok := n.Cities[c3.Id]; ok {
-                        continue
-                    }
-                    if p2 := getPresence(c3); p2 != 0 && b.adjacentCity(b.Cities[c2], c3) {
-                        n.Score += p2
-                        n.Cities[c3.Id] = struct{}{}
-                        newFrontier = append(newFrontier, c3.Id)
-                    }
-                }
-            }
-            frontier = newFrontier
-        }
-        networks = append(networks, n)
-    }
-
-    max := 0
-    for _, n := range networks {
-        if n.Score > max {
-            max = n.Score
+        root := n.find(c)
+        scores[root] += p
+        if scores[root] > max {
+            max = scores[root]
         }
     }
     return max
 }
 
-func containsCity(c City, cs []City) bool {
-    for _, c2 := range cs {
-        if c.Id == c2.Id {
-            return true
-        }
+func (b Board) Equals(that Board) bool {
+    if b.Name != that.Name || len(b.Cities) != len(that.Cities) || len(b.Routes) != len(that.Routes) {
+        return false
     }
-    return false
-}
-
-func (b *Board) adjacentCity(x, y City) bool {
-    for _, r := range b.Routes {
-        if (r.LeftCityId == x.Id && r.RightCityId == y.Id) ||
-            (r.RightCityId == x.Id && r.LeftCityId == y.Id) {
-            return true
+    for i, c := range b.Cities {
+        if !c.Equals(that.Cities[i]) {
+            return false
         }
     }
-    return false
-}
-
-/*
-func (b Board) DeepEquals(that Board) bool {
-    return b.Name == that.Name &&
-        deepEqualsCities(b.Cities, that.Cities) &&
-        deepEqualsRoutes(b.Routes, that.Routes)
-}
-
-func deepEqualsCities(this []Cities, that []Cities) bool {
-    for i, c := range this {
-        if !c.deepEquals(that[i]) {
+    for i, r := range b.Routes {
+        if !r.Equals(that.Routes[i]) {
             return false
         }
     }
     return true
 }
 
-*/
+func (b Board) Clone() Board {
+    if b.Cities != nil {
+        cities := make([]City, len(b.Cities))
+        for i, c := range b.Cities {
+            cities[i] = c.Clone()
+        }
+        b.Cities = cities
+    }
+    if b.Routes != nil {
+        routes := make([]Route, len(b.Routes))
+        for i, r := range b.Routes {
+            routes[i] = r.Clone()
+        }
+        b.Routes = routes
+    }
+    return b
+}
@@ -0,0 +1,195 @@
+package simple
+
+import (
+    "sync"
+)
+
+// Which cities and routes touch, worked out once per board layout since
+// pieces never change it.  Cities and Routes are indexed by Id.
+type boardGraph struct {
+    cities int
+    routes int
+
+    // Cities joined by at least one route, by Id.
+    adjacent [][]bool
+    neighbors [][]int
+
+    // Routes sharing a city with each route (not itself), by Id.
+    routeNeighbors [][]int
+
+    // The two BonusTerminus cities, or -1.
+    bonusStart int
+    bonusEnd int
+
+    // The layout this was worked out from: each route's Id and cities, and
+    // which cities are BonusTerminus, in board order.
+    routeEnds [][3]int
+    bonusTermini []bool
+}
+
+// Boards share the graph of the first board seen with the same layout.  Name
+// only narrows the search; nothing stops two boards with one Name from being
+// laid out differently.
+var boardGraphs = map[string][]*boardGraph{}
+var boardGraphsLock sync.RWMutex
+
+// Found on first use and kept on b, so this is only a pointer check after.
+// Boards aren't laid out again once they're played on; anything that does
+// change which cities a route joins must call relayout.
+func (b *Board) graph() *boardGraph {
+    if b.layout == nil {
+        b.layout = sharedBoardGraph(b)
+    }
+    return b.layout
+}
+
+func (b *Board) relayout() {
+    b.layout = nil
+}
+
+func sharedBoardGraph(b *Board) *boardGraph {
+    boardGraphsLock.RLock()
+    for _, g := range boardGraphs[b.Name] {
+        if g.laidOut(b) {
+            boardGraphsLock.RUnlock()
+            return g
+        }
+    }
+    boardGraphsLock.RUnlock()
+
+    g := newBoardGraph(b)
+    boardGraphsLock.Lock()
+    defer boardGraphsLock.Unlock()
+    for _, g2 := range boardGraphs[b.Name] {
+        if g2.laidOut(b) {
+            return g2
+        }
+    }
+    boardGraphs[b.Name] = append(boardGraphs[b.Name], g)
+    return g
+}
+
+// Whether g is the graph of b's layout.
+func (g *boardGraph) laidOut(b *Board) bool {
+    if len(g.routeEnds) != len(b.Routes) || len(g.bonusTermini) != len(b.Cities) {
+        return false
+    }
+    for i, r := range b.Routes {
+        if g.routeEnds[i] != [3]int{r.Id, r.LeftCityId, r.RightCityId} {
+            return false
+        }
+    }
+    for i, c := range b.Cities {
+        if g.bonusTermini[i] != c.BonusTerminus {
+            return false
+        }
+    }
+    return true
+}
+
+func newBoardGraph(b *Board) *boardGraph {
+    g := &boardGraph{
+        cities: len(b.Cities),
+        routes: len(b.Routes),
+        adjacent: make([][]bool, len(b.Cities)),
+        neighbors: make([][]int, len(b.Cities)),
+        routeNeighbors: make([][]int, len(b.Routes)),
+        bonusStart: -1,
+        bonusEnd: -1,
+    }
+    for i, _ := range g.adjacent {
+        g.adjacent[i] = make([]bool, len(b.Cities))
+    }
+    for _, r := range b.Routes {
+        g.routeEnds = append(g.routeEnds, [3]int{r.Id, r.LeftCityId, r.RightCityId})
+    }
+    for _, c := range b.Cities {
+        g.bonusTermini = append(g.bonusTermini, c.BonusTerminus)
+    }
+    cityRoutes := make([][]int, len(b.Cities))
+    for _, r := range b.Routes {
+        l, x := r.LeftCityId, r.RightCityId
+        if !g.adjacent[l][x] {
+            g.adjacent[l][x] = true
+            g.adjacent[x][l] = true
+            g.neighbors[l] = append(g.neighbors[l], x)
+            g.neighbors[x] = append(g.neighbors[x], l)
+        }
+        cityRoutes[l] = append(cityRoutes[l], r.Id)
+        if x != l {
+            cityRoutes[x] = append(cityRoutes[x], r.Id)
+        }
+    }
+
+    // In Id order, like a scan over b.Routes would find them.
+    for _, r := range b.Routes {
+        touching := make([]bool, len(b.Routes))
+        for _, c := range []int{r.LeftCityId, r.RightCityId} {
+            for _, r2 := range cityRoutes[c] {
+                if r2 != r.Id {
+                    touching[r2] = true
+                }
+            }
+        }
+        for r2, t := range touching {
+            if t {
+                g.routeNeighbors[r.Id] = append(g.routeNeighbors[r.Id], r2)
+            }
+        }
+    }
+
+    for _, c := range b.Cities {
+        if c.BonusTerminus {
+            if g.bonusStart == -1 {
+                g.bonusStart = c.Id
+            } else if g.bonusEnd == -1 {
+                g.bonusEnd = c.Id
+            }
+        }
+    }
+    return g
+}
+
+// Union-find over cities, for networks.
+type cityNetworks struct {
+    parent []int
+}
+
+func newCityNetworks(cities int) cityNetworks {
+    n := cityNetworks{parent: make([]int, cities)}
+    for i, _ := range n.parent {
+        n.parent[i] = i
+    }
+    return n
+}
+
+func (n cityNetworks) find(c int) int {
+    for n.parent[c] != c {
+        n.parent[c] = n.parent[n.parent[c]]
+        c = n.parent[c]
+    }
+    return c
+}
+
+func (n cityNetworks) union(x, y int) {
+    x, y = n.find(x), n.find(y)
+    if x != y {
+        n.parent[y] = x
+    }
+}
+
+// Joins every pair of adjacent cities that both have presence.
+func (g *boardGraph) networks(presence []int) cityNetworks {
+    n := newCityNetworks(g.cities)
+    for c, p := range presence {
+        if p == 0 {
+            continue
+        }
+        for _, c2 := range g.neighbors[c] {
+            if c2 > c && presence[c2] != 0 {
+                n.union(c, c2)
+            }
+        }
+    }
+    return n
+}
@@ -0,0 +1,180 @@
+package simple
+
+import (
+    "math/rand"
+    "testing"
+)
+
+// Boards with one Name but different routes mustn't share a graph.
+func TestBoardGraphLayout(t *testing.T) {
+    b := NewBase45Board()
+    same := NewBase45Board()
+    if b.graph() != same.graph() {
+        t.Errorf("two Base45 boards have different graphs")
+    }
+
+    moved := NewBase45Board()
+    moved.graph()
+    r := &moved.Routes[0]
+    r.RightCityId = (r.RightCityId + 1) % len(moved.Cities)
+    if r.RightCityId == r.LeftCityId {
+        r.RightCityId = (r.RightCityId + 1) % len(moved.Cities)
+    }
+    moved.relayout()
+    g := moved.graph()
+    if g == b.graph() {
+        t.Fatalf("a board with route 0 moved shares Base45's graph")
+    }
+    if !g.adjacent[r.LeftCityId][r.RightCityId] {
+        t.Errorf("route 0 moved to join %d and %d, but they aren't adjacent", r.LeftCityId, r.RightCityId)
+    }
+}
+
+// How networks were scored before the graph: a BFS over the cities, checking
+// every route for adjacency.
+func bfsNetworkScore(b *Board, presence []int) int {
+    seen := make([]bool, len(b.Cities))
+    max := 0
+    for _, c := range b.Cities {
+        if presence[c.Id] == 0 || seen[c.Id] {
+            continue
+        }
+        seen[c.Id] = true
+        score := presence[c.Id]
+        frontier := []int{c.Id}
+        for ;len(frontier)>0; {
+            newFrontier := []int{}
+            for _, c2 := range frontier {
+                for _, c3 := range b.Cities {
+                    if !seen[c3.Id] && presence[c3.Id] != 0 && bfsAdjacent(b, c2, c3.Id) {
+                        seen[c3.Id] = true
+                        score += presence[c3.Id]
+                        newFrontier = append(newFrontier, c3.Id)
+                    }
+                }
+            }
+            frontier = newFrontier
+        }
+        if score > max {
+            max = score
+        }
+    }
+    return max
+}
+
+func bfsBonusRouteCompleted(b *Board, presence []int) bool {
+    termini := []int{}
+    for _, c := range b.Cities {
+        if c.BonusTerminus {
+            termini = append(termini, c.Id)
+        }
+    }
+    if len(termini) < 2 || presence[termini[0]] == 0 || presence[termini[1]] == 0 {
+        return false
+    }
+    seen := make([]bool, len(b.Cities))
+    seen[termini[0]] = true
+    frontier := []int{termini[0]}
+    for ;len(frontier)>0; {
+        newFrontier := []int{}
+        for _, c2 := range frontier {
+            for _, c3 := range b.Cities {
+                if !seen[c3.Id] && presence[c3.Id] != 0 && bfsAdjacent(b, c2, c3.Id) {
+                    if c3.Id == termini[1] {
+                        return true
+                    }
+                    seen[c3.Id] = true
+                    newFrontier = append(newFrontier, c3.Id)
+                }
+            }
+        }
+        frontier = newFrontier
+    }
+    return false
+}
+
+func bfsAdjacent(b *Board, x, y int) bool {
+    for _, r := range b.Routes {
+        if (r.LeftCityId == x && r.RightCityId == y) || (r.RightCityId == x && r.LeftCityId == y) {
+            return true
+        }
+    }
+    return false
+}
+
+// A Base45 board with color in each city presence[Id] times, in virtual
+// offices (which count the same as offices).
+func presenceBoard(color PlayerColor, presence []int) Board {
+    b := NewBase45Board()
+    for i, p := range presence {
+        for j:=0;j<p;j++ {
+            b.Cities[i].VirtualOffices = append(b.Cities[i].VirtualOffices, Piece{PlayerColor: color, Shape: CubeShape})
+        }
+    }
+    return b
+}
+
+func randomPresence(rng *rand.Rand, cities int) []int {
+    presence := make([]int, cities)
+    density := rng.Float64()
+    for i, _ := range presence {
+        if rng.Float64() < density {
+            presence[i] = 1 + rng.Intn(3)
+        }
+    }
+    return presence
+}
+
+func TestNetworksMatchBFS(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    cities := len(NewBase45Board().Cities)
+    completed := 0
+    for i:=0;i<2000;i++ {
+        presence := randomPresence(rng, cities)
+        b := presenceBoard(RedPlayerColor, presence)
+        if got, want := b.GetNetworkScore(RedPlayerColor), bfsNetworkScore(&b, presence); got != want {
+            t.Fatalf("presence %v: network score %d, BFS says %d", presence, got, want)
+        }
+        c := rng.Intn(cities)
+        if got, want := b.GetNetworkScoreIfCity(RedPlayerColor, c), bfsNetworkScore(&b, ifCity(presence, c)); got != want {
+            t.Fatalf("presence %v: network score if city %d %d, BFS says %d", presence, c, got, want)
+        }
+        got, want := b.GetBonusRouteCompleted(RedPlayerColor), bfsBonusRouteCompleted(&b, presence)
+        if got != want {
+            t.Fatalf("presence %v: bonus route completed %t, BFS says %t", presence, got, want)
+        }
+        if got {
+            completed++
+        }
+    }
+    if completed == 0 {
+        t.Errorf("no presence completed the bonus route, so it went untested")
+    }
+}
+
+func ifCity(presence []int, c int) []int {
+    r := append([]int{}, presence...)
+    r[c]++
+    return r
+}
+
+func BenchmarkNetworkScore(b *testing.B) {
+    rng := rand.New(rand.NewSource(1))
+    board := presenceBoard(RedPlayerColor, randomPresence(rng, len(NewBase45Board().Cities)))
+    b.ResetTimer()
+    for i:=0;i<b.N;i++ {
+        board.GetNetworkScore(RedPlayerColor)
+        board.GetBonusRouteCompleted(RedPlayerColor)
+    }
+}
+
+func BenchmarkNetworkScoreBFS(b *testing.B) {
+    rng := rand.New(rand.NewSource(1))
+    presence := randomPresence(rng, len(NewBase45Board().Cities))
+    board := presenceBoard(RedPlayerColor, presence)
+    b.ResetTimer()
+    for i:=0;i<b.N;i++ {
+        bfsNetworkScore(&board, presence)
+        bfsBonusRouteCompleted(&board, presence)
+    }
+}
@@ -90,3 +90,33 @@ func containsColor(c PlayerColor, cs []PlayerColor) bool {
     }
     return false
 }
+
+func (c City) Clone() City {
+    if c.Offices != nil {
+        c.Offices = append(make([]Office, 0, len(c.Offices)), c.Offices...)
+    }
+    c.VirtualOffices = clonePieces(c.VirtualOffices)
+    if c.Coellen.Spots != nil {
+        c.Coellen.Spots = append(make([]CoellenSpot, 0, len(c.Coellen.Spots)), c.Coellen.Spots...)
+    }
+    return c
+}
+
+func (c City) Equals(that City) bool {
+    if c.Id != that.Id || c.Name != that.Name || c.Award != that.Award || c.BonusTerminus != that.BonusTerminus ||
+        len(c.Offices) != len(that.Offices) || len(c.Coellen.Spots) != len(that.Coellen.Spots) ||
+        !equalPieces(c.VirtualOffices, that.VirtualOffices) {
+        return false
+    }
+    for i, o := range c.Offices {
+        if o != that.Offices[i] {
+            return false
+        }
+    }
+    for i, s := range c.Coellen.Spots {
+        if s != that.Coellen.Spots[i] {
+            return false
+        }
+    }
+    return true
+}
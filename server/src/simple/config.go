@@ -88,7 +88,7 @@ func LoadConfig(filename string) Config {
     stack, ok := configs[stackName]
     if !ok {
         now := time.Now().Format("2006-01-02T15:04:05.000Z")
-        fmt.Printf("%s: LoadConfig config unknown stack '%s' set in '%s', goodbye.\n", now, stack, filename)
+        fmt.Printf("%s: LoadConfig config unknown stack '%s' set in '%s', goodbye.\n", now, stackName, filename)
         os.Exit(1)
     }
 
@@ -0,0 +1,7 @@
+// +build debug
+
+package simple
+
+// Built with -tags debug: games check their table after every subaction (see
+// Table.CheckInvariants).
+const DebugBuild = true
@@ -0,0 +1,22 @@
+package simple
+
+// How well a server bot plays.  NoneDifficulty is whatever the bot plays at by
+// default (Hard).
+type Difficulty int
+const (
+    NoneDifficulty Difficulty = iota
+    BeginnerDifficulty
+    EasyDifficulty
+    MediumDifficulty
+    HardDifficulty
+    ExpertDifficulty
+)
+
+var DifficultyNames = map[Difficulty]string{
+    NoneDifficulty: "Default",
+    BeginnerDifficulty: "Beginner",
+    EasyDifficulty: "Easy",
+    MediumDifficulty: "Medium",
+    HardDifficulty: "Hard",
+    ExpertDifficulty: "Expert",
+}
@@ -0,0 +1,59 @@
+package simple
+
+import (
+    "fmt"
+)
+
+// How checkStatus orders PlayerBoards when the game starts.
+type TurnOrder int
+const (
+    RandomTurnOrder TurnOrder = iota
+    SeatTurnOrder
+    ReverseRankTurnOrder // of the previous game (a rematch), else random
+)
+
+var TurnOrderNames = map[TurnOrder]string{
+    RandomTurnOrder: "Random",
+    SeatTurnOrder: "Seat",
+    ReverseRankTurnOrder: "ReverseRank",
+}
+
+// How long seated bots take over their moves.  Whatever it is, they move
+// instantly while no humans are watching.
+type BotPacing int
+const (
+    HumanBotPacing BotPacing = iota // longer over harder decisions
+    SpectatorBotPacing // waits for each move's animations
+    InstantBotPacing // for tests and simulations
+)
+
+var BotPacingNames = map[BotPacing]string{
+    HumanBotPacing: "Human",
+    SpectatorBotPacing: "Spectator",
+    InstantBotPacing: "Instant",
+}
+
+// Set by the creator while a game is Creating.
+type GameOptions struct {
+    TurnOrder TurnOrder
+
+    // Minutes per player, 0 for untimed.  Used for matchmaking; the game
+    // doesn't enforce it yet.
+    TimeControl int
+
+    BotPacing BotPacing
+}
+
+// Returns what's wrong with the options, or "" if nothing.
+func (o GameOptions) Validate() string {
+    if _, ok := TurnOrderNames[o.TurnOrder]; !ok {
+        return fmt.Sprintf("Not a valid turn order: %d", o.TurnOrder)
+    }
+    if o.TimeControl < 0 {
+        return fmt.Sprintf("Not a valid time control: %d", o.TimeControl)
+    }
+    if _, ok := BotPacingNames[o.BotPacing]; !ok {
+        return fmt.Sprintf("Not a valid bot pacing: %d", o.BotPacing)
+    }
+    return ""
+}
@@ -11,15 +11,19 @@ const (
     IdentityTypeConnection
     IdentityTypeGuest
     IdentityTypeBot
+    IdentityTypeExternalBot
 )
 var IdentityTypeNames = map[IdentityType]string {
     IdentityTypeNone: "IdentityTypeNone",
     IdentityTypeConnection: "IdentityTypeConnection",
     IdentityTypeGuest: "IdentityTypeGuest",
     IdentityTypeBot: "IdentityTypeBot",
+    IdentityTypeExternalBot: "IdentityTypeExternalBot",
 }
 
-// Id prefix: G = Guest, P = Player, B = Bot, "" is the EmptyIdentity.
+// Id prefix: G = Guest, P = Player, B = Bot, E = Engine Bot (a local process
+// over stdio, still IdentityTypeBot), X = External Bot (its own process over
+// the websocket API), "" is the EmptyIdentity.
 type Identity struct {
     Id string
     Name string
@@ -44,6 +48,9 @@ func NewGuestIdentity(id string) Identity {
 func NewBotIdentity(id string, name string) Identity {
     return NewIdentity(id, name, IdentityTypeBot)
 }
+func NewExternalBotIdentity(id string, name string) Identity {
+    return NewIdentity(id, name, IdentityTypeExternalBot)
+}
 
 var EmptyIdentity = NewIdentity("", "", IdentityTypeNone)
 
@@ -0,0 +1,268 @@
+package simple
+
+import (
+    "fmt"
+)
+
+// What each player puts in their Stock and Supply when the game starts, on
+// top of what starts on their tracks (see NewPlayerBoard).
+const (
+    StartCubes = 11
+    StartDiscs = 1
+)
+
+// Puts StartCubes and StartDiscs in each player's Supply and Stock, as the
+// game starts in turn order: the disc and 5 cubes in the first player's
+// Supply, one more cube for each player after, and the rest in Stock.
+func (t *Table) DealStartPieces() {
+    for i, _ := range t.PlayerBoards {
+        pb := &t.PlayerBoards[i]
+        pb.Supply[0] = Piece{pb.Color, DiscShape}
+        for i2:=0;i2<StartCubes;i2++ {
+            if i2 < 5+i {
+                pb.Supply[i2+1] = Piece{pb.Color, CubeShape}
+            } else {
+                pb.Stock[i2-(5+i)] = Piece{pb.Color, CubeShape}
+            }
+        }
+    }
+}
+
+// Everything physically wrong with t: pieces appearing or disappearing,
+// pieces where their color or shape can't go, bumped pieces nobody bumped,
+// and tokens appearing or disappearing.  Returns nil when there is nothing
+// wrong.  A table before the game starts (nothing in any Stock or Supply yet)
+// is fine too.
+func (t *Table) CheckInvariants() []string {
+    c := newInvariantChecker(t)
+    c.check()
+    return c.violations
+}
+
+// As CheckInvariants, and a bumped piece is only where ts says a bump is
+// waiting to be resolved.
+func (t *Table) CheckInvariantsWith(ts TurnState) []string {
+    c := newInvariantChecker(t)
+    c.check()
+    c.bumps(ts)
+    return c.violations
+}
+
+type invariantChecker struct {
+    t *Table
+    violations []string
+
+    // Pieces found of each color, by Shape, not counting tracks.
+    played map[PlayerColor][]int
+    tracks map[PlayerColor][]int
+}
+
+func newInvariantChecker(t *Table) *invariantChecker {
+    c := &invariantChecker{
+        t: t,
+        played: map[PlayerColor][]int{},
+        tracks: map[PlayerColor][]int{},
+    }
+    for _, pb := range t.PlayerBoards {
+        c.played[pb.Color] = make([]int, DiscShape+1)
+        c.tracks[pb.Color] = make([]int, DiscShape+1)
+    }
+    return c
+}
+
+func (c *invariantChecker) violation(msg string, fargs ...interface{}) {
+    c.violations = append(c.violations, fmt.Sprintf(msg, fargs...))
+}
+
+func (c *invariantChecker) check() {
+    c.board()
+    for i, pb := range c.t.PlayerBoards {
+        c.playerBoard(i, pb)
+    }
+    c.conserved()
+    c.tokens()
+}
+
+// Counts p (if any) and returns whether it's a piece of a color at the table.
+func (c *invariantChecker) piece(p Piece, counts map[PlayerColor][]int, where string, fargs ...interface{}) bool {
+    if p == (Piece{}) {
+        return false
+    }
+    if p.Shape != CubeShape && p.Shape != DiscShape {
+        c.violation("%s: piece %v has no Shape", fmt.Sprintf(where, fargs...), p)
+        return false
+    }
+    if _, ok := counts[p.PlayerColor]; !ok {
+        c.violation("%s: %s piece, but nobody is %s", fmt.Sprintf(where, fargs...),
+            PlayerColorNames[p.PlayerColor], PlayerColorNames[p.PlayerColor])
+        return false
+    }
+    counts[p.PlayerColor][p.Shape]++
+    return true
+}
+
+func (c *invariantChecker) board() {
+    bumped := 0
+    for _, r := range c.t.Board.Routes {
+        if len(r.Bumped) != len(r.Spots) {
+            c.violation("Route %d: %d bumped slots for %d spots", r.Id, len(r.Bumped), len(r.Spots))
+        }
+        for i, p := range r.Spots {
+            c.piece(p, c.played, "Route %d spot %d", r.Id, i)
+        }
+        for i, p := range r.Bumped {
+            if !c.piece(p, c.played, "Route %d bumped %d", r.Id, i) {
+                continue
+            }
+            bumped++
+            if i >= len(r.Spots) || r.Spots[i] == (Piece{}) {
+                c.violation("Route %d bumped %d: %s was bumped, but nothing took the spot",
+                    r.Id, i, PlayerColorNames[p.PlayerColor])
+            } else if r.Spots[i].PlayerColor == p.PlayerColor {
+                c.violation("Route %d bumped %d: %s bumped themself", r.Id, i, PlayerColorNames[p.PlayerColor])
+            }
+        }
+    }
+    if bumped > 1 {
+        c.violation("%d bumped pieces, but bumps are resolved one at a time", bumped)
+    }
+
+    for _, city := range c.t.Board.Cities {
+        for i, o := range city.Offices {
+            if c.piece(o.Piece, c.played, "City %d (%s) office %d", city.Id, city.Name, i) && o.Piece.Shape != o.Shape {
+                c.violation("City %d (%s) office %d: %s in a %s office",
+                    city.Id, city.Name, i, ShapeNames[o.Piece.Shape], ShapeNames[o.Shape])
+            }
+        }
+        for i, p := range city.VirtualOffices {
+            c.piece(p, c.played, "City %d (%s) virtual office %d", city.Id, city.Name, i)
+        }
+        for i, s := range city.Coellen.Spots {
+            if c.piece(s.Piece, c.played, "City %d (%s) Coellen spot %d", city.Id, city.Name, i) && s.Piece.Shape != DiscShape {
+                c.violation("City %d (%s) Coellen spot %d: %s isn't a disc", city.Id, city.Name, i, ShapeNames[s.Piece.Shape])
+            }
+        }
+    }
+}
+
+func (c *invariantChecker) playerBoard(player int, pb PlayerBoard) {
+    own := func(ps []Piece, counts map[PlayerColor][]int, shape Shape, name string) {
+        for i, p := range ps {
+            if !c.piece(p, counts, "Player %d %s %d", player, name, i) {
+                continue
+            }
+            if p.PlayerColor != pb.Color {
+                c.violation("Player %d %s %d: %s piece on %s's board",
+                    player, name, i, PlayerColorNames[p.PlayerColor], PlayerColorNames[pb.Color])
+            }
+            if shape != NoneShape && p.Shape != shape {
+                c.violation("Player %d %s %d: %s on a %s track", player, name, i, ShapeNames[p.Shape], ShapeNames[shape])
+            }
+        }
+    }
+    own(pb.Keys, c.tracks, CubeShape, "Keys")
+    own(pb.Actions, c.tracks, CubeShape, "Actions")
+    own(pb.Priviledge, c.tracks, CubeShape, "Priviledge")
+    own(pb.Books, c.tracks, DiscShape, "Books")
+    own(pb.Bags, c.tracks, CubeShape, "Bags")
+    own(pb.Stock, c.played, NoneShape, "Stock")
+    own(pb.Supply, c.played, NoneShape, "Supply")
+}
+
+// Pieces only ever move, so each color has what it started with: its tracks
+// when the board was made, plus StartCubes and StartDiscs once the game
+// starts.
+func (c *invariantChecker) conserved() {
+    for _, pb := range c.t.PlayerBoards {
+        want := NewPlayerBoard(EmptyIdentity, pb.Color)
+        cubes := left(want.Keys) + left(want.Actions) + left(want.Priviledge) + left(want.Bags)
+        discs := left(want.Books)
+        played := c.played[pb.Color]
+        if played[CubeShape] + played[DiscShape] > 0 {
+            cubes += StartCubes
+            discs += StartDiscs
+        }
+        tracks := c.tracks[pb.Color]
+        if got := played[CubeShape] + tracks[CubeShape]; got != cubes {
+            c.violation("%s has %d cubes, but started with %d", PlayerColorNames[pb.Color], got, cubes)
+        }
+        if got := played[DiscShape] + tracks[DiscShape]; got != discs {
+            c.violation("%s has %d discs, but started with %d", PlayerColorNames[pb.Color], got, discs)
+        }
+    }
+}
+
+// Tokens only ever move too: each of NewBaseTokens is somewhere, and so is
+// each of NewBaseStartTokens once the game starts.  Start tokens never go in
+// the pile we draw from.
+func (c *invariantChecker) tokens() {
+    found := map[Token]int{}
+    count := func(t Token, where string, fargs ...interface{}) {
+        if t < NoneToken || t > Remove3Token {
+            c.violation("%s: token %d doesn't exist", fmt.Sprintf(where, fargs...), t)
+            return
+        }
+        if t != NoneToken {
+            found[t]++
+        }
+    }
+    for i, t := range c.t.Tokens {
+        count(t, "Token pile %d", i)
+        if isStartToken(t) {
+            c.violation("Token pile %d: start token %d in the pile", i, t)
+        }
+    }
+    for _, r := range c.t.Board.Routes {
+        count(r.Token, "Route %d", r.Id)
+    }
+    for i, pb := range c.t.PlayerBoards {
+        for i2, t := range pb.UnusedTokens {
+            count(t, "Player %d unused token %d", i, i2)
+        }
+        for i2, t := range pb.UsedTokens {
+            count(t, "Player %d used token %d", i, i2)
+        }
+    }
+
+    want := map[Token]int{}
+    for _, t := range NewBaseTokens() {
+        want[t]++
+    }
+    started := false
+    for _, t := range NewBaseStartTokens() {
+        if found[t] > 0 {
+            started = true
+        }
+    }
+    if started {
+        for _, t := range NewBaseStartTokens() {
+            want[t]++
+        }
+    }
+    for t:=StartVirtualOfficeToken;t<=Remove3Token;t++ {
+        if found[t] != want[t] {
+            c.violation("%d of token %d, but there should be %d", found[t], t, want[t])
+        }
+    }
+}
+
+func isStartToken(t Token) bool {
+    return t == StartVirtualOfficeToken || t == StartSwapOfficesToken || t == StartRemove3Token
+}
+
+// Only the piece at ts.BumpingLocation is bumped, and only until it moves.
+func (c *invariantChecker) bumps(ts TurnState) {
+    bumping := (ts.Type == BumpPaying || ts.Type == Bumping) && !ts.BumpingMoved
+    for _, r := range c.t.Board.Routes {
+        for i, p := range r.Bumped {
+            at := bumping && ts.BumpingLocation.Id == r.Id && ts.BumpingLocation.Index == i
+            if p != (Piece{}) && !at {
+                c.violation("Route %d bumped %d: %s is bumped, but no bump of it is in progress",
+                    r.Id, i, PlayerColorNames[p.PlayerColor])
+            }
+            if p == (Piece{}) && at {
+                c.violation("Route %d bumped %d: empty, but the bumped piece hasn't moved", r.Id, i)
+            }
+        }
+    }
+}
@@ -0,0 +1,61 @@
+package simple
+
+import (
+    "math/rand"
+    "strings"
+    "testing"
+)
+
+// Each breaks a freshly started table in one way CheckInvariants must catch.
+func TestCheckInvariantsViolations(t *testing.T) {
+    tests := []struct{
+        name string
+        breaks func(t *Table)
+        want string // in some violation
+    }{
+        {"extra cube in Stock", func(t *Table) {
+            pb := &t.PlayerBoards[0]
+            for i, p := range pb.Stock {
+                if p == (Piece{}) {
+                    pb.Stock[i] = Piece{pb.Color, CubeShape}
+                    break
+                }
+            }
+        }, "cubes, but started with"},
+        {"bumped slot with no bump", func(t *Table) {
+            // Both pieces come out of their Supply, so none appear.
+            r := &t.Board.Routes[0]
+            r.Spots[0], t.PlayerBoards[1].Supply[1] = t.PlayerBoards[1].Supply[1], Piece{}
+            r.Bumped[0], t.PlayerBoards[0].Supply[1] = t.PlayerBoards[0].Supply[1], Piece{}
+        }, "no bump of it is in progress"},
+        {"duplicated token", func(t *Table) {
+            t.PlayerBoards[0].UnusedTokens = append(t.PlayerBoards[0].UnusedTokens, t.Tokens[0])
+        }, "of token"},
+        {"disc in a cube office", func(t *Table) {
+            for i, c := range t.Board.Cities {
+                if len(c.Offices) > 0 && c.Offices[0].Shape == CubeShape {
+                    pb := &t.PlayerBoards[0]
+                    t.Board.Cities[i].Offices[0].Piece, pb.Supply[0] = pb.Supply[0], Piece{}
+                    return
+                }
+            }
+        }, "Disc in a Cube office"},
+    }
+    for _, test := range tests {
+        table := newTestTable(rand.New(rand.NewSource(1)), 4)
+        if v := table.CheckInvariantsWith(NoneTurnState); len(v) != 0 {
+            t.Fatalf("a started table breaks invariants: %v", v)
+        }
+        test.breaks(&table)
+        v := table.CheckInvariantsWith(NoneTurnState)
+        found := false
+        for _, s := range v {
+            if strings.Contains(s, test.want) {
+                found = true
+            }
+        }
+        if !found {
+            t.Errorf("%s: want a violation with '%s', got %v", test.name, test.want, v)
+        }
+    }
+}
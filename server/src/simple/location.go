@@ -25,6 +25,8 @@ type Location struct {
     //     6: Supply (where you play from)
     //     7: Token Unused
     //     8: Token Used
+    // For tokens (7 and 8), Subindex is where in the list; a token added at
+    // Subindex goes before the one there (or at the end, at len).
     Subindex int
 }
 
@@ -0,0 +1,5 @@
+// +build !debug
+
+package simple
+
+const DebugBuild = false
@@ -4,3 +4,24 @@ type Piece struct {
     PlayerColor PlayerColor
     Shape Shape
 }
+
+// Nil stays nil, so a clone marshals the same as the original.
+func clonePieces(ps []Piece) []Piece {
+    if ps == nil {
+        return nil
+    }
+    return append(make([]Piece, 0, len(ps)), ps...)
+}
+
+// Nil and empty are equal.
+func equalPieces(x, y []Piece) bool {
+    if len(x) != len(y) {
+        return false
+    }
+    for i, p := range x {
+        if p != y[i] {
+            return false
+        }
+    }
+    return true
+}
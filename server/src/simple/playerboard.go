@@ -166,3 +166,29 @@ func leftmost(track []Piece) int {
     return 100
 }
 
+func (p PlayerBoard) Clone() PlayerBoard {
+    p.UnusedTokens = cloneTokens(p.UnusedTokens)
+    p.UsedTokens = cloneTokens(p.UsedTokens)
+    p.Stock = clonePieces(p.Stock)
+    p.Supply = clonePieces(p.Supply)
+    p.Keys = clonePieces(p.Keys)
+    p.Priviledge = clonePieces(p.Priviledge)
+    p.Books = clonePieces(p.Books)
+    p.Actions = clonePieces(p.Actions)
+    p.Bags = clonePieces(p.Bags)
+    return p
+}
+
+func (p PlayerBoard) Equals(that PlayerBoard) bool {
+    return p.Identity == that.Identity &&
+        p.Color == that.Color &&
+        equalTokens(p.UnusedTokens, that.UnusedTokens) &&
+        equalTokens(p.UsedTokens, that.UsedTokens) &&
+        equalPieces(p.Stock, that.Stock) &&
+        equalPieces(p.Supply, that.Supply) &&
+        equalPieces(p.Keys, that.Keys) &&
+        equalPieces(p.Priviledge, that.Priviledge) &&
+        equalPieces(p.Books, that.Books) &&
+        equalPieces(p.Actions, that.Actions) &&
+        equalPieces(p.Bags, that.Bags)
+}
@@ -9,3 +9,19 @@ type Route struct {
     LeftCityId int
     RightCityId int
 }
+
+func (r Route) Clone() Route {
+    r.Spots = clonePieces(r.Spots)
+    r.Bumped = clonePieces(r.Bumped)
+    return r
+}
+
+func (r Route) Equals(that Route) bool {
+    return r.Id == that.Id &&
+        equalPieces(r.Spots, that.Spots) &&
+        equalPieces(r.Bumped, that.Bumped) &&
+        r.Token == that.Token &&
+        r.StartToken == that.StartToken &&
+        r.LeftCityId == that.LeftCityId &&
+        r.RightCityId == that.RightCityId
+}
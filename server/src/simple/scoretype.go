@@ -12,3 +12,19 @@ const (
     PlaceScoreType
 )
 
+func CloneScores(scores []map[ScoreType]int) []map[ScoreType]int {
+    if scores == nil {
+        return nil
+    }
+    r := make([]map[ScoreType]int, len(scores))
+    for i, s := range scores {
+        if s == nil {
+            continue
+        }
+        r[i] = map[ScoreType]int{}
+        for t, v := range s {
+            r[i][t] = v
+        }
+    }
+    return r
+}
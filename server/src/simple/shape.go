@@ -6,3 +6,9 @@ const (
     CubeShape
     DiscShape
 )
+
+var ShapeNames = map[Shape]string{
+    NoneShape: "None",
+    CubeShape: "Cube",
+    DiscShape: "Disc",
+}
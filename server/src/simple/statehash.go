@@ -0,0 +1,174 @@
+package simple
+
+import (
+    "fmt"
+    "strconv"
+)
+
+// A digest of everything on a table that affects play (not who is seated or
+// any times), so two tables with the same pieces in the same places hash the
+// same however they got there.  It's FNV-1a over a fixed walk of the table.
+// It goes over the wire as 16 hex digits, since javascript can't hold it as a
+// number.
+type StateHash uint64
+
+func (h StateHash) String() string {
+    return fmt.Sprintf("%016x", uint64(h))
+}
+
+func (h StateHash) MarshalText() ([]byte, error) {
+    return []byte(h.String()), nil
+}
+
+func (h *StateHash) UnmarshalText(b []byte) error {
+    x, err := strconv.ParseUint(string(b), 16, 64)
+    if err != nil {
+        return fmt.Errorf("bad StateHash '%s': %s", b, err)
+    }
+    *h = StateHash(x)
+    return nil
+}
+
+func (t *Table) Hash() StateHash {
+    h := newHasher()
+    h.table(t)
+    return StateHash(h)
+}
+
+// The table and what we're waiting for on it, for telling positions apart in
+// searches.
+func (t *Table) HashWith(ts TurnState) StateHash {
+    h := newHasher()
+    h.table(t)
+    h.turnState(ts)
+    return StateHash(h)
+}
+
+type hasher uint64
+
+const (
+    fnvOffset = 14695981039346656037
+    fnvPrime = 1099511628211
+)
+
+func newHasher() hasher {
+    return fnvOffset
+}
+
+func (h *hasher) int(x int) {
+    v := uint64(x)
+    for i:=0;i<8;i++ {
+        *h ^= hasher(v & 0xff)
+        *h *= fnvPrime
+        v >>= 8
+    }
+}
+
+func (h *hasher) bool(b bool) {
+    if b {
+        h.int(1)
+    } else {
+        h.int(0)
+    }
+}
+
+func (h *hasher) string(s string) {
+    h.int(len(s))
+    for i:=0;i<len(s);i++ {
+        *h ^= hasher(s[i])
+        *h *= fnvPrime
+    }
+}
+
+func (h *hasher) piece(p Piece) {
+    h.int(int(p.PlayerColor))
+    h.int(int(p.Shape))
+}
+
+func (h *hasher) pieces(ps []Piece) {
+    h.int(len(ps))
+    for _, p := range ps {
+        h.piece(p)
+    }
+}
+
+func (h *hasher) tokens(ts []Token) {
+    h.int(len(ts))
+    for _, t := range ts {
+        h.int(int(t))
+    }
+}
+
+func (h *hasher) location(l Location) {
+    h.int(int(l.Type))
+    h.int(l.Id)
+    h.int(l.Index)
+    h.int(l.Subindex)
+}
+
+func (h *hasher) table(t *Table) {
+    h.string(t.Board.Name)
+    h.int(len(t.Board.Cities))
+    for _, c := range t.Board.Cities {
+        h.int(len(c.Offices))
+        for _, o := range c.Offices {
+            h.int(int(o.Shape))
+            h.int(int(o.Priviledge))
+            h.piece(o.Piece)
+            h.bool(o.Virtual)
+            h.int(o.Points)
+        }
+        h.pieces(c.VirtualOffices)
+        h.int(len(c.Coellen.Spots))
+        for _, s := range c.Coellen.Spots {
+            h.int(int(s.Priviledge))
+            h.piece(s.Piece)
+            h.int(s.Points)
+        }
+        h.int(int(c.Award))
+        h.bool(c.BonusTerminus)
+    }
+    h.int(len(t.Board.Routes))
+    for _, r := range t.Board.Routes {
+        h.pieces(r.Spots)
+        h.pieces(r.Bumped)
+        h.int(int(r.Token))
+        h.bool(r.StartToken)
+        h.int(r.LeftCityId)
+        h.int(r.RightCityId)
+    }
+    h.int(len(t.PlayerBoards))
+    for _, pb := range t.PlayerBoards {
+        h.int(int(pb.Color))
+        h.tokens(pb.UnusedTokens)
+        h.tokens(pb.UsedTokens)
+        h.pieces(pb.Stock)
+        h.pieces(pb.Supply)
+        h.pieces(pb.Keys)
+        h.pieces(pb.Priviledge)
+        h.pieces(pb.Books)
+        h.pieces(pb.Actions)
+        h.pieces(pb.Bags)
+    }
+    h.int(len(t.Scores))
+    for _, s := range t.Scores {
+        h.int(s)
+    }
+    h.tokens(t.Tokens)
+}
+
+func (h *hasher) turnState(ts TurnState) {
+    h.int(int(ts.Type))
+    h.int(ts.Player)
+    h.int(ts.ActionsLeft)
+    h.int(ts.BagsLeft)
+    h.int(ts.BumpPayingCost)
+    h.int(ts.BumpingPlayer)
+    h.location(ts.BumpingLocation)
+    h.bool(ts.BumpingMoved)
+    h.int(ts.BumpingReplaces)
+    h.int(ts.MovesLeft)
+    h.int(int(ts.ClearingAward))
+    h.int(ts.ClearingRouteId)
+    h.bool(ts.ClearingCanOffice)
+}
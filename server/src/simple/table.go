@@ -9,9 +9,10 @@ import (
 // any business logic about playing the game.  If a moved piece bumps into
 // another piece, they trade places (this is abused for office swapping).
 // ValidateLocation and ValidateLocationAndPiece reference the contained Board
-// and PlayerBoards to make sure that a location makes sense.  ApplySubaction
-// mutates the Board and PlayerBoard with the given subaction; only subactions
-// who's Source and Dest have been validated should be used.
+// and PlayerBoards to make sure that a location makes sense, and
+// ValidateSubaction that a move does.  ApplySubaction mutates the Board and
+// PlayerBoard with the given subaction; only validated subactions should be
+// used.
 type Table struct {
     Board Board
     PlayerBoards []PlayerBoard
@@ -29,6 +30,62 @@ func (t *Table) ValidateLocationAndPiece(l Location, p Piece, token Token) strin
     return t.validateLocation(l, true, p, token)
 }
 
+// Validate that s is a move the table can make and undo: its Source has its
+// Piece or Token, its Dest exists, and it is a plain move, a bump onto a route
+// spot, or a swap between offices.  The rules of the game are the game's.
+// Idempotent.
+func (t *Table) ValidateSubaction(s Subaction) string {
+    if err := t.ValidateLocationAndPiece(s.Source, s.Piece, s.Token); err != "" {
+        return err
+    }
+    if err := t.ValidateLocation(s.Dest); err != "" {
+        return err
+    }
+    if s.Source == s.Dest {
+        return ""
+    }
+    tokens := s.Dest.Type == PlayerLocationType && s.Dest.Index > 6
+    if s.Token != NoneToken {
+        if s.Dest.Type == CityLocationType || (s.Dest.Type == PlayerLocationType && !tokens) {
+            return "Tokens only go on routes or to a player's tokens"
+        }
+        if s.Dest.Type == RouteLocationType && t.Board.Routes[s.Dest.Id].Token != NoneToken {
+            return "Route already has a token"
+        }
+        return ""
+    }
+
+    if tokens {
+        return "Pieces don't go with tokens"
+    }
+    if s.Dest.Type == RouteLocationType && s.Dest.Subindex == 1 {
+        return "Pieces are only bumped, not moved, into a bumped spot"
+    }
+    destP := t.GetPiece(s.Dest)
+    if destP != (Piece{}) {
+        if s.Dest.Type == RouteLocationType {
+            bumped := Location{RouteLocationType, s.Dest.Id, s.Dest.Index, 1}
+            if t.GetPiece(bumped) != (Piece{}) {
+                return "A piece there is already bumped"
+            }
+        } else if s.Source.Type != CityLocationType || s.Dest.Type != CityLocationType {
+            return "There is already a piece in Dest (only offices swap)"
+        }
+    }
+
+    // See applyVirtualOffice; a swap doesn't empty it.
+    swap := destP != (Piece{}) && s.Dest.Type == CityLocationType
+    if s.Source.Type == CityLocationType && s.Source.Subindex == 1 && !swap {
+        if s.Source.Index != len(t.Board.Cities[s.Source.Id].VirtualOffices) - 1 {
+            return "Only the last virtual office can be emptied"
+        }
+        if s.Dest.Type == CityLocationType && s.Dest.Subindex == 1 && s.Dest.Id == s.Source.Id {
+            return "Virtual office is already in that city"
+        }
+    }
+    return ""
+}
+
 func (t *Table) ApplySubactions(ss []Subaction, i Identity) {
     for _, s := range ss {
         t.ApplySubaction(s, i)
@@ -56,10 +113,15 @@ func (t *Table) UndoSubaction(s Subaction) {
     }, NewConnectionIdentity("P2342", "Undo"))
 }
 
-// Note: only call this if you have validated the Dest and validated the Source
-// + Piece.  Otherwise you may explode.
+// Note: only call this with a subaction that passes ValidateSubaction.
+// Otherwise you may explode.
 func (t *Table) ApplySubaction(s Subaction, i Identity) {
     //fmt.Println("%s: %s", i, s)
+
+    // Otherwise a piece "bumps" itself, leaving two of it.
+    if s.Source == s.Dest {
+        return
+    }
     if s.Token != NoneToken {
         t.removeToken(s.Source, s.Token)
         t.addToken(s.Dest, s.Token)
@@ -110,8 +172,9 @@ func (t *Table) ApplySubaction(s Subaction, i Identity) {
             panic(fmt.Sprintf("Attempted to bump but there is something in the bump spot (%s): %v", i, s))
         }
 
-        // TODO: TEMP
-        if s.Source.Type == PlayerLocationType || s.Dest.Type == PlayerLocationType {
+        // Only offices swap.  A swap off of a route couldn't be undone: undoing
+        // it moves onto the route, which bumps.
+        if s.Source.Type != CityLocationType || s.Dest.Type != CityLocationType {
             panic(fmt.Sprintf("Attempt to apply illegal swap (%s): (%v) (%s)", i, s, t.JsonPretty()))
         }
 
@@ -134,6 +197,11 @@ func (t *Table) GetPiece(l Location) Piece {
             return t.Board.Cities[l.Id].Coellen.Spots[l.Index].Piece
         }
         if l.Subindex == 1 {
+
+            // The next virtual office, which doesn't exist yet.
+            if l.Index == len(t.Board.Cities[l.Id].VirtualOffices) {
+                return Piece{}
+            }
             return t.Board.Cities[l.Id].VirtualOffices[l.Index]
         }
         return t.Board.Cities[l.Id].Offices[l.Index].Piece
@@ -160,33 +228,40 @@ func (t *Table) GetPiece(l Location) Piece {
 }
 
 // Assumes location is of RouteLocationType.  BFS until one empty spot is found
-// starting with (and not including) this route.
+// starting with (and not including) this route.  Empty if every route it can
+// reach is full.
 func (t *Table) ValidBumps(l Location) []Location {
+    g := t.Board.graph()
     open := []Location{}
-    oldFrontier := []Route{t.Board.Routes[l.Id]}
-    frontier := []Route{}
+    seen := make([]bool, len(t.Board.Routes))
+    seen[l.Id] = true
+    oldFrontier := []int{l.Id}
     for d:=1;len(open)==0;d++ {
+        frontier := []int{}
         for _, or := range oldFrontier {
-            for _, nr := range t.Board.Routes {
-                if !containsRoute(nr, oldFrontier) && adjacentRoute(or, nr) {
+            for _, nr := range g.routeNeighbors[or] {
+                if !seen[nr] {
+                    seen[nr] = true
                     frontier = append(frontier, nr)
                 }
             }
         }
         for _, r := range frontier {
-            for i, p := range r.Spots {
+            for i, p := range t.Board.Routes[r].Spots {
                 if p == (Piece{}) {
                     open = append(open, Location{
                         Type: RouteLocationType,
-                        Id: r.Id,
+                        Id: r,
                         Index: i,
                         Subindex: 0,
                     })
                 }
             }
         }
-        oldFrontier = append(oldFrontier, frontier...)
-        frontier = []Route{}
+        if len(frontier) == 0 {
+            break
+        }
+        oldFrontier = frontier
         if d > 10 {
             panic(fmt.Sprintf("ValidBumps unable to find opening from location %v.  Table: %v", l, t))
         }
@@ -214,6 +289,9 @@ func (t *Table) validateLocation(l Location, p bool, piece Piece, token Token) s
     if l.Type == NoneLocationType {
         return "Location has no Type (0)"
     }
+    if l.Type < NoneLocationType || l.Type > PlayerLocationType {
+        return fmt.Sprintf("Location Type %d doesn't exist", l.Type)
+    }
     isToken := token != NoneToken
     if p {
         if isToken {
@@ -237,7 +315,10 @@ func (t *Table) validateLocation(l Location, p bool, piece Piece, token Token) s
             return fmt.Sprintf("Route Spot %d doesn't exist", l.Index)
         }
         if l.Subindex < 0 || l.Subindex > 1 {
-            return fmt.Sprintf("Route invalid subindex (0=normal, 1=bumped)", l.Subindex)
+            return fmt.Sprintf("Route invalid subindex %d (0=normal, 1=bumped)", l.Subindex)
+        }
+        if l.Subindex == 1 && l.Index >= len(t.Board.Routes[l.Id].Bumped) {
+            return fmt.Sprintf("Route Bumped spot %d doesn't exist", l.Index)
         }
         if p {
             if isToken {
@@ -260,7 +341,7 @@ func (t *Table) validateLocation(l Location, p bool, piece Piece, token Token) s
             return fmt.Sprintf("City %d doesn't exist", l.Id)
         }
         if l.Subindex < 0 || l.Subindex > 2 {
-            return fmt.Sprintf("City invalid subindex (0=normal, 1=virtual, 2=coellen)", l.Subindex)
+            return fmt.Sprintf("City invalid subindex %d (0=normal, 1=virtual, 2=coellen)", l.Subindex)
         }
         if l.Subindex == 2 {
             if l.Index < 0 || t.Board.Cities[l.Id].Coellen.Spots == nil || l.Index >= len(t.Board.Cities[l.Id].Coellen.Spots) {
@@ -282,7 +363,8 @@ func (t *Table) validateLocation(l Location, p bool, piece Piece, token Token) s
                         return "Coellen piece  does not exist"
                     }
                 } else if l.Subindex == 1 {
-                    if piece != t.Board.Cities[l.Id].VirtualOffices[l.Index] {
+                    if l.Index == len(t.Board.Cities[l.Id].VirtualOffices) ||
+                        piece != t.Board.Cities[l.Id].VirtualOffices[l.Index] {
                         return "Virtual office piece does not exist"
                     }
                 } else {
@@ -321,19 +403,25 @@ func (t *Table) validateLocation(l Location, p bool, piece Piece, token Token) s
         if l.Index == 6 && (l.Subindex < 0 || l.Subindex >= len(t.PlayerBoards[l.Id].Supply)) {
             return "Supply does not have that subindex"
         }
+
+        // Tokens go in before Subindex; len adds one at the end.
+        if l.Index == 7 && (l.Subindex < 0 || l.Subindex > len(t.PlayerBoards[l.Id].UnusedTokens)) {
+            return "Unused tokens do not have that subindex"
+        }
+        if l.Index == 8 && (l.Subindex < 0 || l.Subindex > len(t.PlayerBoards[l.Id].UsedTokens)) {
+            return "Used tokens do not have that subindex"
+        }
         if p {
             if isToken {
                 if l.Index < 7 {
                     return "No tokens on this section of player board"
                 }
-                if l.Index == 7 && !ContainsToken(t.PlayerBoards[l.Id].UnusedTokens, token) {
+                if l.Index == 7 && !tokenAt(t.PlayerBoards[l.Id].UnusedTokens, l.Subindex, token) {
                     return "Token does not exist"
                 }
-                if l.Index == 8 && !ContainsToken(t.PlayerBoards[l.Id].UsedTokens, token) {
+                if l.Index == 8 && !tokenAt(t.PlayerBoards[l.Id].UsedTokens, l.Subindex, token) {
                     return "Token does not exist"
                 }
-                // TODO: Validate new Subindex field here depending on how we
-                // implement tokens.
             } else {
                 if l.Index == 0 && t.PlayerBoards[l.Id].Keys[l.Subindex] != piece {
                     return "Piece does not exist"
@@ -371,12 +459,12 @@ func (t *Table) addToken(l Location, token Token) {
     }
     if l.Type == PlayerLocationType {
         if l.Index == 7 {
-            t.PlayerBoards[l.Id].UnusedTokens = append(
-                t.PlayerBoards[l.Id].UnusedTokens, token)
+            t.PlayerBoards[l.Id].UnusedTokens = insertToken(
+                t.PlayerBoards[l.Id].UnusedTokens, l.Subindex, token)
         }
         if l.Index == 8 {
-            t.PlayerBoards[l.Id].UsedTokens = append(
-                t.PlayerBoards[l.Id].UsedTokens, token)
+            t.PlayerBoards[l.Id].UsedTokens = insertToken(
+                t.PlayerBoards[l.Id].UsedTokens, l.Subindex, token)
         }
     }
 }
@@ -387,16 +475,36 @@ func (t *Table) removeToken(l Location, token Token) {
     }
     if l.Type == PlayerLocationType {
         if l.Index == 7 {
-            t.PlayerBoards[l.Id].UnusedTokens = RemoveToken(
-                t.PlayerBoards[l.Id].UnusedTokens, token)
+            t.PlayerBoards[l.Id].UnusedTokens = removeTokenAt(
+                t.PlayerBoards[l.Id].UnusedTokens, l.Subindex, token)
         }
         if l.Index == 8 {
-            t.PlayerBoards[l.Id].UsedTokens = RemoveToken(
-                t.PlayerBoards[l.Id].UsedTokens, token)
+            t.PlayerBoards[l.Id].UsedTokens = removeTokenAt(
+                t.PlayerBoards[l.Id].UsedTokens, l.Subindex, token)
         }
     }
 }
 
+// Placing on the next virtual office opens it, and emptying the last one
+// closes it again, so undoing a new virtual office leaves the city as it was.
+// Only the last can be emptied; a gap couldn't be closed and reopened.
+func (t *Table) applyVirtualOffice(l Location, p Piece) {
+    c := &t.Board.Cities[l.Id]
+    if l.Index == len(c.VirtualOffices) {
+        if p != (Piece{}) {
+            c.VirtualOffices = append(c.VirtualOffices, p)
+        }
+        return
+    }
+    if p == (Piece{}) {
+        if l.Index != len(c.VirtualOffices)-1 {
+            panic(fmt.Sprintf("Attempt to empty virtual office %d of %d in %s", l.Index, len(c.VirtualOffices), c.Name))
+        }
+        c.VirtualOffices = c.VirtualOffices[:l.Index]
+        return
+    }
+    c.VirtualOffices[l.Index] = p
+}
 
 // Assumes valid Location
 func (t *Table) applyPiece(l Location, p Piece) {
@@ -411,7 +519,7 @@ func (t *Table) applyPiece(l Location, p Piece) {
         if l.Subindex == 2 {
             t.Board.Cities[l.Id].Coellen.Spots[l.Index].Piece = p
         } else if l.Subindex == 1 {
-            t.Board.Cities[l.Id].VirtualOffices[l.Index] = p
+            t.applyVirtualOffice(l, p)
         } else {
             t.Board.Cities[l.Id].Offices[l.Index].Piece = p
         }
@@ -437,18 +545,45 @@ func (t *Table) applyPiece(l Location, p Piece) {
     }
 }
 
-func containsRoute(r Route, rs []Route) bool {
-    for _, r2 := range rs {
-        if r.Id == r2.Id {
-            return true
+// A deep copy that shares nothing with t; much cheaper than a round trip
+// through Json.
+func (t *Table) Clone() Table {
+    r := Table{
+        Board: t.Board.Clone(),
+        Scores: CloneInts(t.Scores),
+        Tokens: cloneTokens(t.Tokens),
+    }
+    if t.PlayerBoards != nil {
+        r.PlayerBoards = make([]PlayerBoard, len(t.PlayerBoards))
+        for i, pb := range t.PlayerBoards {
+            r.PlayerBoards[i] = pb.Clone()
         }
     }
-    return false
+    return r
+}
+
+func CloneInts(xs []int) []int {
+    if xs == nil {
+        return nil
+    }
+    return append(make([]int, 0, len(xs)), xs...)
 }
 
-func adjacentRoute(r1 Route, r2 Route) bool {
-    return r1.LeftCityId == r2.LeftCityId ||
-        r1.LeftCityId == r2.RightCityId ||
-        r1.RightCityId == r2.LeftCityId ||
-        r1.RightCityId == r2.RightCityId
+// Everything, including who is seated (which Hash leaves out).
+func (t *Table) Equals(that *Table) bool {
+    if !t.Board.Equals(that.Board) || len(t.PlayerBoards) != len(that.PlayerBoards) ||
+        len(t.Scores) != len(that.Scores) || !equalTokens(t.Tokens, that.Tokens) {
+        return false
+    }
+    for i, pb := range t.PlayerBoards {
+        if !pb.Equals(that.PlayerBoards[i]) {
+            return false
+        }
+    }
+    for i, s := range t.Scores {
+        if s != that.Scores[i] {
+            return false
+        }
+    }
+    return true
 }
@@ -0,0 +1,375 @@
+package simple
+
+import (
+    "math/rand"
+    "testing"
+)
+
+// A table just after Game starts one: start tokens on their routes, and each
+// player's disc and cubes in their Supply and Stock.
+func newTestTable(rng *rand.Rand, players int) Table {
+    t := Table{
+        Board: NewBase45Board(),
+        PlayerBoards: NewBasePlayerBoards()[:players],
+        Tokens: NewBaseTokens(),
+    }
+    st := NewBaseStartTokens()
+    rng.Shuffle(len(st), func(i, j int) { st[i], st[j] = st[j], st[i] })
+    for i, r := range t.Board.Routes {
+        if r.StartToken {
+            t.Board.Routes[i].Token = st[0]
+            st = st[1:]
+        }
+    }
+    t.DealStartPieces()
+    return t
+}
+
+// Every place on t a piece or token can be, or be moved to.  Route tokens are
+// at spot 0.
+func testLocations(t *Table) []Location {
+    r := []Location{}
+    for _, route := range t.Board.Routes {
+        for i, _ := range route.Spots {
+            r = append(r, Location{RouteLocationType, route.Id, i, 0})
+            r = append(r, Location{RouteLocationType, route.Id, i, 1})
+        }
+    }
+    for _, c := range t.Board.Cities {
+        for i, _ := range c.Offices {
+            r = append(r, Location{CityLocationType, c.Id, i, 0})
+        }
+        for i:=0;i<=len(c.VirtualOffices);i++ {
+            r = append(r, Location{CityLocationType, c.Id, i, 1})
+        }
+        for i, _ := range c.Coellen.Spots {
+            r = append(r, Location{CityLocationType, c.Id, i, 2})
+        }
+    }
+    for id, pb := range t.PlayerBoards {
+        sections := [][]Piece{pb.Keys, pb.Actions, pb.Priviledge, pb.Books, pb.Bags, pb.Stock, pb.Supply}
+        for index, section := range sections {
+            for i, _ := range section {
+                r = append(r, Location{PlayerLocationType, id, index, i})
+            }
+        }
+        for i:=0;i<=len(pb.UnusedTokens);i++ {
+            r = append(r, Location{PlayerLocationType, id, 7, i})
+        }
+        for i:=0;i<=len(pb.UsedTokens);i++ {
+            r = append(r, Location{PlayerLocationType, id, 8, i})
+        }
+    }
+    return r
+}
+
+func isTokenLocation(l Location) bool {
+    return l.Type == PlayerLocationType && l.Index > 6
+}
+
+// Whether s passes validation and leaves t physically possible.
+func legalSubaction(t *Table, s Subaction) bool {
+    if t.ValidateSubaction(s) != "" {
+        return false
+    }
+
+    after := t.Clone()
+    after.ApplySubaction(s, EmptyIdentity)
+    return len(after.CheckInvariants()) == 0
+}
+
+// Applies up to n random legal subactions to t and returns them.
+func randomSubactions(rng *rand.Rand, t *Table, n int) []Subaction {
+    r := []Subaction{}
+    for len(r) < n {
+        locations := testLocations(t)
+        sources := []Subaction{}
+        for _, l := range locations {
+            if isTokenLocation(l) {
+                pb := t.PlayerBoards[l.Id]
+                ts := pb.UnusedTokens
+                if l.Index == 8 {
+                    ts = pb.UsedTokens
+                }
+                if l.Subindex < len(ts) {
+                    sources = append(sources, Subaction{Source: l, Token: ts[l.Subindex]})
+                }
+                continue
+            }
+            if p := t.GetPiece(l); p != (Piece{}) {
+                sources = append(sources, Subaction{Source: l, Piece: p})
+            }
+            if l.Type == RouteLocationType && l.Index == 0 && l.Subindex == 0 && t.Board.Routes[l.Id].Token != NoneToken {
+                sources = append(sources, Subaction{Source: l, Token: t.Board.Routes[l.Id].Token})
+            }
+        }
+
+        found := false
+        for tries:=0;tries<200 && !found;tries++ {
+            s := sources[rng.Intn(len(sources))]
+            s.Dest = locations[rng.Intn(len(locations))]
+            if s.Token != NoneToken && s.Dest.Type == RouteLocationType {
+                s.Dest.Index = 0
+                s.Dest.Subindex = 0
+            }
+            if legalSubaction(t, s) {
+                t.ApplySubaction(s, EmptyIdentity)
+                r = append(r, s)
+                found = true
+            }
+        }
+        if !found {
+            return r
+        }
+    }
+    return r
+}
+
+// Some way into a game, n more subactions and undoing them changes nothing.
+func checkUndo(t *testing.T, seed int64, n int) {
+    rng := rand.New(rand.NewSource(seed))
+    table := newTestTable(rng, 2+rng.Intn(4))
+    randomSubactions(rng, &table, rng.Intn(n))
+    before := table.Clone()
+    ss := randomSubactions(rng, &table, 1+rng.Intn(n))
+    table.UndoSubactions(ss)
+    if !table.Equals(&before) {
+        t.Fatalf("seed %d: undoing %v changed the table\nbefore: %s\nafter: %s", seed, ss, before.Json(), table.Json())
+    }
+    if table.Hash() != before.Hash() {
+        t.Fatalf("seed %d: equal tables hash differently (%s, %s)", seed, before.Hash(), table.Hash())
+    }
+}
+
+func TestUndoSubactionsRestoresTable(t *testing.T) {
+    for seed:=int64(0);seed<200;seed++ {
+        checkUndo(t, seed, 60)
+    }
+}
+
+// Each of these is one of ApplySubaction's special cases, from a fresh table;
+// undoing them must put the table back too.
+func TestUndoSpecialCases(t *testing.T) {
+    table := newTestTable(rand.New(rand.NewSource(1)), 3)
+    supply := func(player, i int) Location {
+        return Location{PlayerLocationType, player, 6, i}
+    }
+    spot := Location{RouteLocationType, 0, 0, 0}
+    office := Location{CityLocationType, 0, 0, 0}
+    office2 := Location{CityLocationType, 3, 0, 0}
+    yellow := Piece{YellowPlayerColor, CubeShape}
+    green := Piece{GreenPlayerColor, CubeShape}
+    tokenRoute := Location{RouteLocationType, 0, 0, 0}
+    for i, r := range table.Board.Routes {
+        if r.Token != NoneToken {
+            tokenRoute.Id = i
+            break
+        }
+    }
+    token := table.Board.Routes[tokenRoute.Id].Token
+
+    cases := []struct{
+        name string
+        ss []Subaction
+    }{
+        {"place", []Subaction{{Source: supply(0, 1), Dest: spot, Piece: yellow}}},
+        {"bump", []Subaction{
+            {Source: supply(0, 1), Dest: spot, Piece: yellow},
+            {Source: supply(1, 1), Dest: spot, Piece: green},
+        }},
+        {"unbump", []Subaction{
+            {Source: supply(0, 1), Dest: spot, Piece: yellow},
+            {Source: supply(1, 1), Dest: spot, Piece: green},
+            {Source: spot, Dest: supply(1, 1), Piece: green},
+        }},
+        {"swap", []Subaction{
+            {Source: supply(0, 1), Dest: office, Piece: yellow},
+            {Source: supply(1, 1), Dest: office2, Piece: green},
+            {Source: office, Dest: office2, Piece: yellow},
+        }},
+        {"virtual office", []Subaction{
+            {Source: supply(0, 1), Dest: Location{CityLocationType, 0, 0, 1}, Piece: yellow},
+            {Source: supply(0, 2), Dest: Location{CityLocationType, 0, 1, 1}, Piece: yellow},
+            {Source: Location{CityLocationType, 0, 1, 1}, Dest: supply(0, 2), Piece: yellow},
+        }},
+        {"tokens", []Subaction{
+            {Source: tokenRoute, Dest: Location{PlayerLocationType, 0, 7, 0}, Token: token},
+            {Source: Location{PlayerLocationType, 0, 7, 0}, Dest: Location{PlayerLocationType, 0, 8, 0}, Token: token},
+        }},
+        {"itself", []Subaction{
+            {Source: supply(0, 1), Dest: spot, Piece: yellow},
+            {Source: spot, Dest: spot, Piece: yellow},
+        }},
+    }
+    for _, c := range cases {
+        before := table.Clone()
+        for _, s := range c.ss {
+            if err := table.ValidateSubaction(s); err != "" {
+                t.Fatalf("%s: %v: %s", c.name, s, err)
+            }
+            table.ApplySubaction(s, EmptyIdentity)
+            if v := table.CheckInvariants(); len(v) != 0 {
+                t.Fatalf("%s: %v broke the table: %v", c.name, s, v)
+            }
+        }
+        table.UndoSubactions(c.ss)
+        if !table.Equals(&before) {
+            t.Errorf("%s: undoing changed the table\nbefore: %s\nafter: %s", c.name, before.Json(), table.Json())
+            table = before
+        }
+    }
+}
+
+// Each of these is a move ApplySubaction couldn't make or undo, last after
+// some that set it up; validation must refuse it.
+func TestValidateSubactionRejects(t *testing.T) {
+    supply := func(player, i int) Location {
+        return Location{PlayerLocationType, player, 6, i}
+    }
+    spot := Location{RouteLocationType, 0, 0, 0}
+    yellow := Piece{YellowPlayerColor, CubeShape}
+    green := Piece{GreenPlayerColor, CubeShape}
+    red := Piece{RedPlayerColor, CubeShape}
+    virtual := func(i int) Location {
+        return Location{CityLocationType, 0, i, 1}
+    }
+
+    cases := []struct{
+        name string
+        ss []Subaction
+    }{
+        {"swap off a route", []Subaction{
+            {Source: supply(0, 1), Dest: spot, Piece: yellow},
+            {Source: spot, Dest: supply(0, 2), Piece: yellow},
+        }},
+        {"swap between supplies", []Subaction{
+            {Source: supply(0, 1), Dest: supply(0, 2), Piece: yellow},
+        }},
+        {"into a bumped spot", []Subaction{
+            {Source: supply(0, 1), Dest: Location{RouteLocationType, 0, 0, 1}, Piece: yellow},
+        }},
+        {"bump a bump", []Subaction{
+            {Source: supply(0, 1), Dest: spot, Piece: yellow},
+            {Source: supply(1, 1), Dest: spot, Piece: green},
+            {Source: supply(2, 1), Dest: spot, Piece: red},
+        }},
+        {"piece to tokens", []Subaction{
+            {Source: supply(0, 1), Dest: Location{PlayerLocationType, 0, 7, 0}, Piece: yellow},
+        }},
+        {"empty a middle virtual office", []Subaction{
+            {Source: supply(0, 1), Dest: virtual(0), Piece: yellow},
+            {Source: supply(0, 2), Dest: virtual(1), Piece: yellow},
+            {Source: virtual(0), Dest: supply(0, 1), Piece: yellow},
+        }},
+        {"virtual office onto its own next", []Subaction{
+            {Source: supply(0, 1), Dest: virtual(0), Piece: yellow},
+            {Source: virtual(0), Dest: virtual(1), Piece: yellow},
+        }},
+    }
+    for _, c := range cases {
+        table := newTestTable(rand.New(rand.NewSource(1)), 3)
+        last := len(c.ss) - 1
+        for _, s := range c.ss[:last] {
+            if err := table.ValidateSubaction(s); err != "" {
+                t.Fatalf("%s: setting up with %v: %s", c.name, s, err)
+            }
+            table.ApplySubaction(s, EmptyIdentity)
+        }
+        if table.ValidateSubaction(c.ss[last]) == "" {
+            t.Errorf("%s: %v passed validation", c.name, c.ss[last])
+        }
+    }
+
+    // Tokens only go on routes without one, or to a player.
+    table := newTestTable(rand.New(rand.NewSource(1)), 3)
+    routes := []int{}
+    for i, r := range table.Board.Routes {
+        if r.Token != NoneToken {
+            routes = append(routes, i)
+        }
+    }
+    token := table.Board.Routes[routes[0]].Token
+    from := Location{RouteLocationType, routes[0], 0, 0}
+    for _, dest := range []Location{
+        Location{RouteLocationType, routes[1], 0, 0},
+        Location{CityLocationType, 0, 0, 0},
+        supply(0, 1),
+    } {
+        s := Subaction{Source: from, Dest: dest, Token: token}
+        if table.ValidateSubaction(s) == "" {
+            t.Errorf("token to %v passed validation", dest)
+        }
+    }
+}
+
+// GetPiece, failing t instead of panicking.
+func getPiece(t *testing.T, table *Table, l Location) (p Piece) {
+    defer func() {
+        if r := recover(); r != nil {
+            t.Fatalf("GetPiece(%v) panicked on a validated location: %v", l, r)
+        }
+    }()
+    return table.GetPiece(l)
+}
+
+// A location validation accepts is one GetPiece can read, and (with a piece)
+// has that piece.
+func checkValidatedLocation(t *testing.T, table *Table, l Location, p Piece) {
+    if table.ValidateLocation(l) == "" && !isTokenLocation(l) {
+        getPiece(t, table, l)
+    }
+    if table.ValidateLocationAndPiece(l, p, NoneToken) == "" {
+        if got := getPiece(t, table, l); got != p {
+            t.Fatalf("%v validated with %v, but has %v", l, p, got)
+        }
+    }
+}
+
+func TestValidatedLocationsExist(t *testing.T) {
+    for seed:=int64(0);seed<20;seed++ {
+        rng := rand.New(rand.NewSource(seed))
+        table := newTestTable(rng, 2+rng.Intn(4))
+        randomSubactions(rng, &table, rng.Intn(60))
+        for _, l := range testLocations(&table) {
+            for _, d := range []int{-1, 0, 1} {
+                for _, near := range []Location{
+                    {l.Type + LocationType(d), l.Id, l.Index, l.Subindex},
+                    {l.Type, l.Id + d, l.Index, l.Subindex},
+                    {l.Type, l.Id, l.Index + d, l.Subindex},
+                    {l.Type, l.Id, l.Index, l.Subindex + d},
+                } {
+                    p := Piece{}
+                    if !isTokenLocation(near) && table.ValidateLocation(near) == "" {
+                        p = getPiece(t, &table, near)
+                    }
+                    checkValidatedLocation(t, &table, near, p)
+                    checkValidatedLocation(t, &table, near, Piece{YellowPlayerColor, CubeShape})
+                }
+            }
+        }
+    }
+}
+
+func FuzzUndoSubactions(f *testing.F) {
+    for seed:=int64(0);seed<8;seed++ {
+        f.Add(seed, uint8(60))
+    }
+    f.Fuzz(func(t *testing.T, seed int64, n uint8) {
+        checkUndo(t, seed, int(n)+1)
+    })
+}
+
+func FuzzValidateLocationAndPiece(f *testing.F) {
+    f.Add(int64(0), int(RouteLocationType), 3, 1, 1, int(YellowPlayerColor), int(CubeShape))
+    f.Add(int64(1), int(CityLocationType), 4, 0, 1, int(GreenPlayerColor), int(DiscShape))
+    f.Add(int64(2), int(PlayerLocationType), 1, 6, 0, int(GreenPlayerColor), int(DiscShape))
+    f.Add(int64(3), int(PlayerLocationType), 0, 7, 0, int(YellowPlayerColor), int(CubeShape))
+    f.Add(int64(4), 9, 0, 0, 0, 0, 0)
+    f.Fuzz(func(t *testing.T, seed int64, lt, id, index, subindex, color, shape int) {
+        rng := rand.New(rand.NewSource(seed))
+        table := newTestTable(rng, 2+rng.Intn(4))
+        randomSubactions(rng, &table, rng.Intn(40))
+        l := Location{LocationType(lt), id, index, subindex}
+        checkValidatedLocation(t, &table, l, Piece{PlayerColor(color), Shape(shape)})
+    })
+}
@@ -31,3 +31,42 @@ func RemoveToken(ts []Token, x Token) []Token {
     }
     return ts
 }
+
+func tokenAt(ts []Token, i int, x Token) bool {
+    return i >= 0 && i < len(ts) && ts[i] == x
+}
+
+// Puts x before ts[i], or at the end if there is no ts[i].
+func insertToken(ts []Token, i int, x Token) []Token {
+    if i < 0 || i >= len(ts) {
+        return append(ts, x)
+    }
+    return append(ts[:i], append([]Token{x}, ts[i:]...)...)
+}
+
+// Takes x out of ts[i], or wherever it is if it isn't there.
+func removeTokenAt(ts []Token, i int, x Token) []Token {
+    if !tokenAt(ts, i, x) {
+        return RemoveToken(ts, x)
+    }
+    return append(ts[:i], ts[i+1:]...)
+}
+
+func cloneTokens(ts []Token) []Token {
+    if ts == nil {
+        return nil
+    }
+    return append(make([]Token, 0, len(ts)), ts...)
+}
+
+func equalTokens(x, y []Token) bool {
+    if len(x) != len(y) {
+        return false
+    }
+    for i, t := range x {
+        if t != y[i] {
+            return false
+        }
+    }
+    return true
+}
@@ -57,4 +57,21 @@ type TurnState struct {
     ClearingCanOffice bool
 }
 
-
+func (ts TurnState) Equals(that TurnState) bool {
+    return ts.Type == that.Type &&
+        ts.Player == that.Player &&
+        ts.ActionsLeft == that.ActionsLeft &&
+        ts.TurnStart.Equal(that.TurnStart) &&
+        ts.TurnElapsedDelta == that.TurnElapsedDelta &&
+        ts.BagsLeft == that.BagsLeft &&
+        ts.BumpPayingCost == that.BumpPayingCost &&
+        ts.BumpingStart.Equal(that.BumpingStart) &&
+        ts.BumpingPlayer == that.BumpingPlayer &&
+        ts.BumpingLocation == that.BumpingLocation &&
+        ts.BumpingMoved == that.BumpingMoved &&
+        ts.BumpingReplaces == that.BumpingReplaces &&
+        ts.MovesLeft == that.MovesLeft &&
+        ts.ClearingAward == that.ClearingAward &&
+        ts.ClearingRouteId == that.ClearingRouteId &&
+        ts.ClearingCanOffice == that.ClearingCanOffice
+}